@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+// tokenTransport serves the OAuth token endpoint from the token cache. It is
+// part of the transport behind the SDK client's relays (see createClient), so
+// the SDK's token requests go through it.
+var tokenTransport *internal.TokenTransport
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the cached OAuth token",
+	Long: `Manage the OAuth access token obtained with --client-id/--client-secret.
+
+The token is cached per profile in the secret backend (see "config set
+secret-backend") and reused by every command until it is about to expire,
+when it is refreshed automatically. This avoids a token request on every
+invocation when running kra-cli in loops.
+
+Examples:
+  # Obtain and cache a token
+  kra-cli auth login
+
+  # Show the cached token's expiry
+  kra-cli auth status
+
+  # Print a valid token for use with other tools
+  curl -H "Authorization: Bearer $(kra-cli auth token)" ...
+
+  # Remove the cached token
+  kra-cli auth logout`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Obtain and cache an OAuth token",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthLogin,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the cached OAuth token",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthStatus,
+}
+
+var authTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print a valid OAuth access token",
+	Long:  `Print a valid OAuth access token, refreshing the cached token if it is near expiry.`,
+	Args:  cobra.NoArgs,
+	RunE:  runAuthToken,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the cached OAuth token",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthLogout,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authTokenCmd)
+	authCmd.AddCommand(authLogoutCmd)
+}
+
+// authStatus describes the cached token for display
+type authStatus struct {
+	Profile   string `json:"profile"`
+	ClientID  string `json:"client_id"`
+	TokenURL  string `json:"token_url"`
+	ExpiresAt string `json:"expires_at"`
+	ExpiresIn string `json:"expires_in"`
+	Status    string `json:"status"`
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	secret, err := clientCredentials()
+	if err != nil {
+		return err
+	}
+
+	cache, err := tokenCache()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	token, err := internal.FetchToken(ctx, nil, tokenURL, clientID, secret)
+	if err != nil {
+		return withExitCode(exitAuth, fmt.Errorf("login failed: %w", err))
+	}
+	if err := cache.Save(token); err != nil {
+		return fmt.Errorf("failed to cache token: %w", err)
+	}
+
+	fmt.Printf("✓ Logged in (profile %s)\n", profileName())
+	fmt.Printf("  Token expires: %s\n", token.ExpiresAt.Local().Format(time.RFC1123))
+	fmt.Printf("  Cached in: %s\n", cache.Store.Location())
+	return nil
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	cache, err := tokenCache()
+	if err != nil {
+		return err
+	}
+
+	token, err := cache.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read cached token: %w", err)
+	}
+	if token == nil {
+		return withExitCode(exitAuth, fmt.Errorf("not logged in (profile %s); run: kra-cli auth login", profileName()))
+	}
+
+	status := authStatus{
+		Profile:   profileName(),
+		ClientID:  token.ClientID,
+		TokenURL:  token.TokenURL,
+		ExpiresAt: token.ExpiresAt.Local().Format(time.RFC3339),
+		Status:    "valid",
+	}
+	remaining := time.Until(token.ExpiresAt).Round(time.Second)
+	switch {
+	case remaining <= 0:
+		status.Status = "expired"
+		status.ExpiresIn = "0s"
+	case remaining <= internal.TokenRefreshMargin:
+		status.Status = "expiring"
+		status.ExpiresIn = remaining.String()
+	default:
+		status.ExpiresIn = remaining.String()
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	return formatter.Print(status)
+}
+
+func runAuthToken(cmd *cobra.Command, args []string) error {
+	secret, err := clientCredentials()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	token, err := useTokenTransport(http.DefaultTransport, secret).Token(ctx, false)
+	if err != nil {
+		return withExitCode(exitAuth, fmt.Errorf("failed to obtain token: %w", err))
+	}
+
+	fmt.Println(token.AccessToken)
+	return nil
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	cache, err := tokenCache()
+	if err != nil {
+		return err
+	}
+
+	if err := cache.Delete(); err != nil {
+		return fmt.Errorf("failed to remove cached token: %w", err)
+	}
+
+	fmt.Printf("✓ Logged out (profile %s)\n", profileName())
+	return nil
+}
+
+// clientCredentials checks that OAuth client credentials are configured and
+// returns the resolved client secret
+func clientCredentials() (string, error) {
+	if err := checkProfile(); err != nil {
+		return "", err
+	}
+	if clientID == "" || clientSecret == "" {
+		return "", withExitCode(exitAuth, fmt.Errorf("OAuth client credentials not configured. Provide --client-id/--client-secret or set them with: kra-cli config set client-id/client-secret"))
+	}
+	if tokenURL == "" {
+		return "", inputErrorf("token URL not configured (see --token-url)")
+	}
+	return resolveSecret("client_secret", clientSecret)
+}
+
+// tokenCache returns the token cache for the active profile
+func tokenCache() (*internal.TokenCache, error) {
+	if err := checkProfile(); err != nil {
+		return nil, err
+	}
+
+	backend := secretBackend()
+	if backend == secretBackendPlain {
+		return nil, inputErrorf("token caching needs the keyring or file secret backend (see: kra-cli config set secret-backend)")
+	}
+
+	store, err := openSecretStore(backend)
+	if err != nil {
+		return nil, err
+	}
+	return &internal.TokenCache{Store: store, Key: secretName(profileName(), "oauth_token")}, nil
+}
+
+// useTokenTransport returns a transport that answers token requests for the
+// current credentials from the token cache and sends everything else to base.
+// Without a usable cache the token is still kept in memory for the rest of
+// the process.
+func useTokenTransport(base http.RoundTripper, secret string) *internal.TokenTransport {
+	tokenTransport = &internal.TokenTransport{
+		Base:         base,
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: secret,
+		OnFetch:      func(err error) { apiMetrics.TokenFetched(err) },
+	}
+	if cache, err := tokenCache(); err == nil {
+		tokenTransport.Cache = cache
+	}
+	return tokenTransport
+}
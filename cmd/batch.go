@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchConcurrency int
+	batchRate        string
+	batchCheckpoint  string
+	batchResume      bool
+	batchErrorsFile  string
+	batchCompareTo   string
+)
+
+// batchSpec describes how a batch command turns its CSV rows into API calls
+type batchSpec[In, Out any] struct {
+	Command string                                 // command name, recorded in the checkpoint journal
+	Key     func(In) string                        // identifies an input in records and the checkpoint journal
+	Call    func(context.Context, In) (Out, error) // performs the API call for one input
+	Valid   func(Out) bool                         // reports whether a successful result is valid
+}
+
+// batchInput is one row of a batch CSV file and the request parsed from it
+type batchInput[In any] struct {
+	Row   internal.InputRow
+	Value In
+	Err   error
+}
+
+// rowError settles a row before any API call with a status other than
+// failed: invalid when its value can never be valid (e.g. a malformed PIN),
+// or skipped when it must not be sent (e.g. an already filed return)
+type rowError struct {
+	status string
+	reason string
+}
+
+func (e *rowError) Error() string { return e.reason }
+
+// invalidInputf reports a row as invalid without calling the API
+func invalidInputf(format string, args ...interface{}) error {
+	return &rowError{status: internal.StatusInvalid, reason: fmt.Sprintf(format, args...)}
+}
+
+// skipInputf reports a row as skipped without calling the API
+func skipInputf(format string, args ...interface{}) error {
+	return &rowError{status: internal.StatusSkipped, reason: fmt.Sprintf(format, args...)}
+}
+
+// addBatchFlags registers the flags shared by every --batch command
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&batchConcurrency, "concurrency", 4, "number of parallel requests in batch mode")
+	cmd.Flags().StringVar(&batchRate, "rate", "5/s", "maximum request rate in batch mode (e.g. 5/s, 300/m, 0 for unlimited)")
+	cmd.Flags().StringVar(&batchCheckpoint, "checkpoint", "", "journal file recording completed batch rows")
+	cmd.Flags().BoolVar(&batchResume, "resume", false, "skip rows already recorded in the --checkpoint journal")
+	cmd.Flags().StringVar(&batchErrorsFile, "errors-file", "", "write invalid and failed rows to this CSV file for reprocessing")
+}
+
+// batchOptions builds the batch engine options from the command flags
+func batchOptions() (internal.BatchOptions, error) {
+	rate, err := internal.ParseRate(batchRate)
+	if err != nil {
+		return internal.BatchOptions{}, inputErrorf("invalid --rate: %w", err)
+	}
+	if batchConcurrency < 1 {
+		return internal.BatchOptions{}, inputErrorf("--concurrency must be at least 1")
+	}
+
+	opts := internal.BatchOptions{
+		Concurrency: batchConcurrency,
+		Rate:        rate,
+	}
+
+	if verbose {
+		opts.OnProgress = func(done, total int) {
+			if done == total || done%100 == 0 {
+				fmt.Fprintf(os.Stderr, "  processed %d/%d\n", done, total)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// parseBatchRows converts every CSV row with parse, keeping rows that are
+// malformed or fail to parse so they are reported rather than dropped
+func parseBatchRows[In any](input *internal.CSVInput, parse func(internal.InputRow) (In, error)) []batchInput[In] {
+	inputs := make([]batchInput[In], len(input.Rows))
+	for i, row := range input.Rows {
+		inputs[i].Row = row
+		if row.Err != nil {
+			inputs[i].Err = inputErrorf("malformed CSV row: %w", row.Err)
+			continue
+		}
+		inputs[i].Value, inputs[i].Err = parse(row)
+	}
+	return inputs
+}
+
+// runBatch runs spec.Call over every parsed input with the shared batch engine
+// and returns one record per input row, in input order. Inputs already
+// recorded in the checkpoint journal are not sent again on --resume, and
+// rejected rows are written to --errors-file in their original CSV shape.
+func runBatch[In, Out any](ctx context.Context, spec batchSpec[In, Out], input *internal.CSVInput, inputs []batchInput[In]) ([]internal.BatchRecord[Out], error) {
+	opts, err := batchOptions()
+	if err != nil {
+		return nil, err
+	}
+	spec.Call = historyCall(spec)
+
+	if batchResume && batchCheckpoint == "" {
+		return nil, inputErrorf("--resume requires --checkpoint")
+	}
+
+	// Check the saved run before spending any API calls
+	if batchCompareTo != "" {
+		if _, err := internal.LoadResultRecords(batchCompareTo); err != nil {
+			return nil, withExitCode(exitInput, err)
+		}
+	}
+
+	var checkpoint *internal.Checkpoint
+	if batchCheckpoint != "" {
+		checkpoint, err = internal.OpenCheckpoint(batchCheckpoint, spec.Command, batchResume)
+		if err != nil {
+			return nil, withExitCode(exitInput, err)
+		}
+		defer checkpoint.Close()
+	}
+
+	records := make([]internal.BatchRecord[Out], len(inputs))
+	pending := make([]int, 0, len(inputs))
+
+	for i, in := range inputs {
+		records[i].Line = in.Row.Line
+		var settled *rowError
+		if errors.As(in.Err, &settled) {
+			records[i].Input = spec.Key(in.Value)
+			records[i].Status = settled.status
+			records[i].Error = in.Err.Error()
+			records[i].Err = in.Err
+			continue
+		}
+		if in.Err != nil {
+			records[i].Status = internal.StatusError
+			records[i].Error = in.Err.Error()
+			records[i].Err = in.Err
+			continue
+		}
+
+		records[i].Input = spec.Key(in.Value)
+
+		// Results already recorded in the checkpoint journal are reused as-is
+		if checkpoint != nil {
+			found, err := checkpoint.Lookup(records[i].Input, &records[i].Result)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				records[i].Status = batchStatus(spec, records[i].Result)
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	if verbose && batchResume {
+		fmt.Fprintf(os.Stderr, "Resuming from %s (%d rows already completed)\n", batchCheckpoint, checkpoint.Len())
+	}
+
+	start := time.Now()
+	results := internal.RunBatch(ctx, pending, opts, func(ctx context.Context, i int) (Out, error) {
+		out, err := spec.Call(ctx, inputs[i].Value)
+		if err == nil && checkpoint != nil {
+			err = checkpoint.Record(records[i].Input, out)
+		}
+		return out, err
+	})
+
+	for _, r := range results {
+		i := pending[r.Index]
+		if r.Err != nil {
+			if ctx.Err() != nil {
+				if checkpoint != nil {
+					return nil, fmt.Errorf("batch interrupted (continue with --checkpoint %s --resume): %w", batchCheckpoint, ctx.Err())
+				}
+				return nil, fmt.Errorf("batch interrupted: %w", ctx.Err())
+			}
+			records[i].Status = internal.StatusError
+			// A row can still be settled when its call is made, e.g. a return
+			// another process filed meanwhile
+			var settled *rowError
+			if errors.As(r.Err, &settled) {
+				records[i].Status = settled.status
+			}
+			records[i].Error = r.Err.Error()
+			records[i].Err = r.Err
+			continue
+		}
+		records[i].Result = r.Value
+		records[i].Status = batchStatus(spec, r.Value)
+	}
+
+	if batchErrorsFile != "" {
+		if err := writeBatchErrors(input, inputs, records); err != nil {
+			return nil, fmt.Errorf("failed to write errors file: %w", err)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, r := range records {
+		counts[r.Status]++
+	}
+	apiMetrics.ObserveBatch(spec.Command, time.Since(start), counts)
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Processed %d rows (%d ok, %d invalid, %d errors, %d skipped)\n",
+			len(records), counts[internal.StatusOK], counts[internal.StatusInvalid], counts[internal.StatusError], counts[internal.StatusSkipped])
+	}
+
+	return records, nil
+}
+
+// batchStatus classifies a successful API result
+func batchStatus[In, Out any](spec batchSpec[In, Out], out Out) string {
+	if spec.Valid(out) {
+		return internal.StatusOK
+	}
+	return internal.StatusInvalid
+}
+
+// addCompareFlag registers --compare-to on a batch lookup command
+func addCompareFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&batchCompareTo, "compare-to", "", "in batch mode, show only what changed since the results saved in this JSON file (see: kra-cli diff)")
+}
+
+// reportBatch prints batch records, sends the --notify notification and
+// returns the exit error for them. With --compare-to only the changes since
+// the saved run are printed and notified, and records that were already
+// invalid do not fail the command; failed rows still do.
+func reportBatch[T any](ctx context.Context, formatter *internal.OutputFormatter, records []internal.BatchRecord[T]) error {
+	if batchCompareTo == "" {
+		if err := formatter.Print(records); err != nil {
+			return err
+		}
+		items := batchNotificationItems(records)
+		notify(ctx, internal.EventBatch, fmt.Sprintf("%d of %d records invalid or failed", len(items), len(records)), items)
+		return batchOutcome(records)
+	}
+
+	changes, compared, err := compareWithSaved(batchCompareTo, records)
+	if err != nil {
+		return err
+	}
+	if err := printChanges(formatter, changes, compared); err != nil {
+		return err
+	}
+	notify(ctx, internal.EventChanges, fmt.Sprintf("%d changes since %s", len(changes), filepath.Base(batchCompareTo)), internal.ChangeItems(changes))
+
+	if err := batchOutcome(records); err != nil && exitCode(err) != exitInvalid {
+		return err
+	}
+	return diffOutcome(changes)
+}
+
+// writeBatchErrors writes every invalid or failed row to --errors-file using
+// the header and field values of the original batch file; rows that could
+// not be parsed are copied as they appear in it. Skipped rows need no
+// reprocessing and are left out.
+func writeBatchErrors[In, Out any](input *internal.CSVInput, inputs []batchInput[In], records []internal.BatchRecord[Out]) error {
+	rows := make([]internal.InputRow, 0)
+	for i, r := range records {
+		if r.Status == internal.StatusOK || r.Status == internal.StatusSkipped {
+			continue
+		}
+		rows = append(rows, inputs[i].Row)
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Writing %d rejected rows to %s\n", len(rows), batchErrorsFile)
+	}
+
+	return internal.WriteInputRows(batchErrorsFile, input.Header, rows)
+}
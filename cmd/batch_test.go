@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestWriteBatchErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pins.csv")
+	content := "pin,name\nP051234567A,Acme\n\"P05\"x,Broken\nP059876543B,Beta\nP051111111C,Gamma\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	input, err := internal.ReadCSVInput(path)
+	if err != nil {
+		t.Fatalf("ReadCSVInput returned error: %v", err)
+	}
+
+	inputs := make([]batchInput[string], len(input.Rows))
+	for i, row := range input.Rows {
+		inputs[i] = batchInput[string]{Row: row, Err: row.Err}
+	}
+	records := []internal.BatchRecord[string]{
+		{Status: internal.StatusOK},
+		{Status: internal.StatusError},
+		{Status: internal.StatusInvalid},
+		{Status: internal.StatusSkipped},
+	}
+
+	batchErrorsFile = filepath.Join(dir, "errors.csv")
+	defer func() { batchErrorsFile = "" }()
+	if err := writeBatchErrors(input, inputs, records); err != nil {
+		t.Fatalf("writeBatchErrors returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(batchErrorsFile)
+	if err != nil {
+		t.Fatalf("failed to read errors file: %v", err)
+	}
+	if want := "pin,name\n\"P05\"x,Broken\nP059876543B,Beta\n"; string(data) != want {
+		t.Errorf("errors file = %q, expected %q", data, want)
+	}
+}
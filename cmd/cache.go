@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheDisabled  bool
+	cacheRefresh   bool
+	cacheEndpoint  string
+	cacheOlderThan string
+)
+
+// cacheTTLKeys maps each cached command to the setting holding its TTL
+var cacheTTLKeys = map[string]string{
+	"verify-pin":   "cache.verify_pin_ttl",
+	"get-taxpayer": "cache.get_taxpayer_ttl",
+}
+
+// cacheStat summarises the cached results of one command
+type cacheStat struct {
+	Command string `json:"command"`
+	TTL     string `json:"ttl"`
+	Entries int    `json:"entries"`
+	Fresh   int    `json:"fresh"`
+	Stale   int    `json:"stale"`
+	Bytes   int64  `json:"bytes"`
+	Oldest  string `json:"oldest,omitempty"`
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean the local result cache",
+	Long: `Inspect and clean the local cache of API results.
+
+verify-pin and get-taxpayer keep their results in a local cache when a TTL is
+configured for them, and answer repeated lookups from it until the TTL passes:
+
+  kra-cli config set cache.verify-pin-ttl 24h
+  kra-cli config set cache.get-taxpayer-ttl 7d
+
+Use --refresh on those commands to fetch and store a new result, or --no-cache
+to bypass the cache entirely.
+
+Examples:
+  kra-cli cache stats
+  kra-cli cache prune
+  kra-cli cache clear --command verify-pin`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cached results per command",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheStats,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached results",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove cached results older than their TTL",
+	Long: `Remove cached results older than the TTL configured for their command, or
+older than --older-than. Results of commands with no TTL are all removed.`,
+	Args: cobra.NoArgs,
+	RunE: runCachePrune,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	cacheClearCmd.Flags().StringVar(&cacheEndpoint, "command", "", "only remove results of this command (verify-pin, get-taxpayer)")
+	cachePruneCmd.Flags().StringVar(&cacheOlderThan, "older-than", "", "remove results older than this instead of the configured TTL (e.g. 12h, 7d)")
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	cache, err := openResultCache()
+	if err != nil {
+		return err
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stats := make(map[string]*cacheStat)
+	for command := range cacheTTLKeys {
+		stats[command] = &cacheStat{Command: command}
+	}
+
+	for _, entry := range entries {
+		stat, ok := stats[entry.Endpoint]
+		if !ok {
+			stat = &cacheStat{Command: entry.Endpoint}
+			stats[entry.Endpoint] = stat
+		}
+		ttl, _ := cacheTTL(entry.Endpoint)
+
+		stat.Entries++
+		stat.Bytes += entry.Size
+		if entry.Fresh(ttl, now) {
+			stat.Fresh++
+		} else {
+			stat.Stale++
+		}
+		if !entry.StoredAt.IsZero() {
+			stored := entry.StoredAt.Local().Format("2006-01-02 15:04")
+			if stat.Oldest == "" || stored < stat.Oldest {
+				stat.Oldest = stored
+			}
+		}
+	}
+
+	rows := make([]cacheStat, 0, len(stats))
+	for command, stat := range stats {
+		stat.TTL = "off"
+		if ttl, err := cacheTTL(command); err != nil {
+			stat.TTL = "invalid"
+		} else if ttl > 0 {
+			stat.TTL = settingString(cacheTTLKeys[command])
+		}
+		rows = append(rows, *stat)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Command < rows[j].Command })
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(rows); err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Cache directory: %s\n", cache.Dir())
+	}
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	cache, err := openResultCache()
+	if err != nil {
+		return err
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+
+	if cacheEndpoint != "" {
+		if _, ok := cacheTTLKeys[cacheEndpoint]; !ok {
+			return inputErrorf("unknown --command %q (use verify-pin or get-taxpayer)", cacheEndpoint)
+		}
+		matching := entries[:0]
+		for _, entry := range entries {
+			if entry.Endpoint == cacheEndpoint {
+				matching = append(matching, entry)
+			}
+		}
+		entries = matching
+	}
+
+	removed, err := cache.Remove(entries)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Removed %d cached results\n", removed)
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	var olderThan time.Duration
+	if cacheOlderThan != "" {
+		d, err := internal.ParseDuration(cacheOlderThan)
+		if err != nil {
+			return inputErrorf("invalid --older-than: %w", err)
+		}
+		olderThan = d
+	}
+
+	cache, err := openResultCache()
+	if err != nil {
+		return err
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stale := make([]internal.CacheEntry, 0)
+	for _, entry := range entries {
+		ttl := olderThan
+		if cacheOlderThan == "" {
+			ttl, err = cacheTTL(entry.Endpoint)
+			if err != nil {
+				return err
+			}
+		}
+		if !entry.Fresh(ttl, now) {
+			stale = append(stale, entry)
+		}
+	}
+
+	removed, err := cache.Remove(stale)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Pruned %d of %d cached results\n", removed, len(entries))
+	return nil
+}
+
+// addCacheFlags registers the flags of a command that caches its results
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&cacheDisabled, "no-cache", false, "neither read nor store cached results")
+	cmd.Flags().BoolVar(&cacheRefresh, "refresh", false, "ignore cached results but store the new ones")
+}
+
+// openResultCache opens the local result cache
+func openResultCache() (*internal.ResultCache, error) {
+	dir, err := dataPath("cache")
+	if err != nil {
+		return nil, err
+	}
+	return internal.NewResultCache(dir), nil
+}
+
+// cacheTTL returns the configured TTL of a command's cached results, or 0
+// when caching is off for it
+func cacheTTL(command string) (time.Duration, error) {
+	key, ok := cacheTTLKeys[command]
+	if !ok {
+		return 0, nil
+	}
+
+	value := settingString(key)
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := internal.ParseDuration(value)
+	if err != nil {
+		return 0, inputErrorf("invalid %s: %w", key, err)
+	}
+	return ttl, nil
+}
+
+// cachedCall wraps an API call so that results for the same identifier are
+// answered from the cache while fresh. Calls pass straight through when no
+// TTL is configured for command or --no-cache is given; with --refresh the
+// API is always called and the cache updated.
+func cachedCall[In, Out any](command string, key func(In) string, call func(context.Context, In) (Out, error)) (func(context.Context, In) (Out, error), error) {
+	ttl, err := cacheTTL(command)
+	if err != nil {
+		return nil, err
+	}
+	if ttl == 0 || cacheDisabled {
+		return call, nil
+	}
+
+	cache, err := openResultCache()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, in In) (Out, error) {
+		k := key(in)
+		if !cacheRefresh {
+			var cached Out
+			hit := cache.Get(command, baseURL, k, ttl, &cached)
+			apiMetrics.CacheLookup(command, hit)
+			if hit {
+				if verbose {
+					fmt.Fprintf(os.Stderr, "  %s: cached result\n", k)
+				}
+				return cached, nil
+			}
+		}
+
+		out, err := call(ctx, in)
+		if err != nil {
+			return out, err
+		}
+		if err := cache.Put(command, baseURL, k, out); err != nil && verbose {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+		return out, nil
+	}, nil
+}
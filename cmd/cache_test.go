@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestCachedCall(t *testing.T) {
+	t.Setenv("KRA_DATA_DIR", t.TempDir())
+	t.Setenv("KRA_CACHE_VERIFY_PIN_TTL", "1h")
+
+	type result struct {
+		PIN   string `json:"pin"`
+		Calls int    `json:"calls"`
+	}
+	calls := 0
+	call := func(ctx context.Context, pin string) (*result, error) {
+		calls++
+		return &result{PIN: pin, Calls: calls}, nil
+	}
+	key := func(pin string) string { return pin }
+
+	lookup := func() *result {
+		t.Helper()
+		fn, err := cachedCall("verify-pin", key, call)
+		if err != nil {
+			t.Fatalf("cachedCall returned error: %v", err)
+		}
+		r, err := fn(context.Background(), "P051234567A")
+		if err != nil {
+			t.Fatalf("call returned error: %v", err)
+		}
+		return r
+	}
+
+	if r := lookup(); r.Calls != 1 {
+		t.Fatalf("expected the first lookup to call the API, got %+v", r)
+	}
+	if r := lookup(); r.Calls != 1 || calls != 1 {
+		t.Fatalf("expected the second lookup to be cached, got %+v after %d calls", r, calls)
+	}
+
+	cacheRefresh = true
+	if r := lookup(); r.Calls != 2 {
+		t.Errorf("expected --refresh to call the API, got %+v", r)
+	}
+	cacheRefresh = false
+	if r := lookup(); r.Calls != 2 {
+		t.Errorf("expected --refresh to store the new result, got %+v", r)
+	}
+
+	cacheDisabled = true
+	defer func() { cacheDisabled = false }()
+	if r := lookup(); r.Calls != 3 {
+		t.Errorf("expected --no-cache to call the API, got %+v", r)
+	}
+
+	t.Setenv("KRA_CACHE_VERIFY_PIN_TTL", "")
+	cacheDisabled = false
+	if r := lookup(); r.Calls != 4 {
+		t.Errorf("expected no caching without a TTL, got %+v", r)
+	}
+}
+
+func TestCachedCallNormalizesPIN(t *testing.T) {
+	t.Setenv("KRA_DATA_DIR", t.TempDir())
+	t.Setenv("KRA_CACHE_VERIFY_PIN_TTL", "1h")
+
+	calls := 0
+	fn, err := cachedCall("verify-pin", internal.NormalizePIN, func(ctx context.Context, pin string) (string, error) {
+		calls++
+		return pin, nil
+	})
+	if err != nil {
+		t.Fatalf("cachedCall returned error: %v", err)
+	}
+
+	for _, pin := range []string{"P051234567A", "p051234567a", " P051234567A "} {
+		if _, err := fn(context.Background(), pin); err != nil {
+			t.Fatalf("call returned error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected one API call for variants of the same PIN, got %d", calls)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+var (
+	recordFile string
+	replayFile string
+
+	// recorder and replayer are created once, so that every client of the
+	// process records to, or replays from, the same cassette
+	recorder *internal.Recorder
+	replayer *internal.Replayer
+)
+
+// withCassette wraps base, the transport to GavaConnect, in the --record
+// recorder, or replaces it with the --replay replayer. The recorder wraps the
+// token cache so the token exchange the SDK sees is recorded too; the
+// replayer replaces the network and the token cache entirely.
+func withCassette(base http.RoundTripper) (http.RoundTripper, error) {
+	if recordFile != "" && replayFile != "" {
+		return nil, inputErrorf("--record and --replay cannot be used together")
+	}
+
+	switch {
+	case replayFile != "":
+		if replayer == nil {
+			cassette, err := internal.LoadCassette(replayFile)
+			if err != nil {
+				return nil, withExitCode(exitInput, err)
+			}
+			replayer = internal.NewReplayer(cassette)
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Replaying %d interactions from %s\n", len(cassette.Interactions), replayFile)
+			}
+		}
+		return replayer, nil
+
+	case recordFile != "":
+		if recorder == nil {
+			recorder = internal.NewRecorder(recordFile, base)
+			if err := recorder.Save(); err != nil {
+				return nil, err
+			}
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Recording HTTP interactions to %s (secrets redacted)\n", recordFile)
+			}
+		}
+		recorder.Base = base
+		return recorder, nil
+	}
+	return base, nil
+}
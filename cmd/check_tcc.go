@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/BerjisTech/kra-cli/internal"
 	kra "github.com/BerjisTech/kra-connect-go-sdk"
@@ -40,6 +38,10 @@ func init() {
 	rootCmd.AddCommand(checkTccCmd)
 	checkTccCmd.Flags().StringVar(&tccBatchFile, "batch", "", "CSV file containing TCCs to check")
 	checkTccCmd.Flags().StringVar(&tccPIN, "pin", "", "Taxpayer PIN associated with the TCC (required when not using --batch)")
+	addBatchFlags(checkTccCmd)
+	addCompareFlag(checkTccCmd)
+	addWatchFlags(checkTccCmd)
+	addNotifyFlag(checkTccCmd)
 }
 
 func runCheckTcc(cmd *cobra.Command, args []string) error {
@@ -49,7 +51,9 @@ func runCheckTcc(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	ctx := context.Background()
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	formatter := internal.NewOutputFormatter(outputFmt)
 
 	if tccBatchFile != "" {
@@ -67,85 +71,74 @@ func runCheckTcc(cmd *cobra.Command, args []string) error {
 		TCCNumber: tcc,
 	}
 
-	result, err := client.VerifyTCC(ctx, req)
+	if watching() {
+		return runWatch(ctx, "TCC "+tcc, watchSingle(checkTCCSpec(client), req))
+	}
+
+	result, err := historyCall(checkTCCSpec(client))(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to check TCC: %w", err)
 	}
 
-	return formatter.Print(result)
-}
-
-func runCheckTccBatch(ctx context.Context, client *kra.Client, formatter *internal.OutputFormatter) error {
-	file, err := os.Open(tccBatchFile)
-	if err != nil {
-		return fmt.Errorf("failed to open batch file: %w", err)
+	if err := formatter.Print(result); err != nil {
+		return err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	return validityOutcome(result.IsValid, "TCC "+tcc)
+}
 
-	header, err := reader.Read()
+func runCheckTccBatch(ctx context.Context, client *kra.Client, formatter *internal.OutputFormatter) error {
+	input, err := internal.ReadCSVInput(tccBatchFile)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
-	}
-
-	tccCol := -1
-	pinCol := -1
-	for i, col := range header {
-		switch strings.ToLower(col) {
-		case "tcc":
-			tccCol = i
-		case "pin":
-			pinCol = i
-		}
+		return withExitCode(exitInput, err)
 	}
 
+	tccCol := input.Column("tcc")
+	pinCol := input.Column("pin")
 	if tccCol == -1 || pinCol == -1 {
-		return fmt.Errorf("CSV file must have 'tcc' and 'pin' columns")
+		return inputErrorf("CSV file must have 'tcc' and 'pin' columns")
 	}
 
-	requests := make([]*kra.TCCVerificationRequest, 0)
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			break
-		}
+	if len(input.Rows) == 0 {
+		return inputErrorf("no TCC/PIN pairs found in CSV file")
+	}
 
-		if tccCol < len(record) && pinCol < len(record) {
-			tccValue := strings.TrimSpace(record[tccCol])
-			pinValue := strings.TrimSpace(record[pinCol])
-			if tccValue == "" || pinValue == "" {
-				continue
-			}
-			requests = append(requests, &kra.TCCVerificationRequest{
-				KraPIN:    pinValue,
-				TCCNumber: tccValue,
-			})
+	inputs := parseBatchRows(input, func(row internal.InputRow) (*kra.TCCVerificationRequest, error) {
+		tccValue := row.Value(tccCol)
+		pinValue := row.Value(pinCol)
+		if tccValue == "" || pinValue == "" {
+			return nil, inputErrorf("missing TCC or PIN")
 		}
-	}
+		return &kra.TCCVerificationRequest{
+			KraPIN:    pinValue,
+			TCCNumber: tccValue,
+		}, nil
+	})
 
-	if len(requests) == 0 {
-		return fmt.Errorf("no TCC/PIN pairs found in CSV file")
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Checking %d TCCs...\n", len(inputs))
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Checking %d TCCs...\n", len(requests))
+	if watching() {
+		return runWatch(ctx, fmt.Sprintf("%d TCCs", len(inputs)), func(ctx context.Context) (interface{}, error) {
+			return runBatch(ctx, checkTCCSpec(client), input, inputs)
+		})
 	}
 
-	results, err := client.VerifyTCCsBatch(ctx, requests)
+	records, err := runBatch(ctx, checkTCCSpec(client), input, inputs)
 	if err != nil {
 		return fmt.Errorf("failed to check TCCs: %w", err)
 	}
 
-	if verbose {
-		validCount := 0
-		for _, r := range results {
-			if r.IsValid {
-				validCount++
-			}
-		}
-		fmt.Fprintf(os.Stderr, "Checked %d TCCs (%d valid, %d invalid)\n", len(results), validCount, len(results)-validCount)
-	}
+	return reportBatch(ctx, formatter, records)
+}
 
-	return formatter.Print(results)
+// checkTCCSpec describes a TCC check
+func checkTCCSpec(client *kra.Client) batchSpec[*kra.TCCVerificationRequest, *kra.TCCVerificationResult] {
+	return batchSpec[*kra.TCCVerificationRequest, *kra.TCCVerificationResult]{
+		Command: "check-tcc",
+		Key:     func(req *kra.TCCVerificationRequest) string { return req.KraPIN + "/" + req.TCCNumber },
+		Call:    meteredCall("check-tcc", client.VerifyTCC),
+		Valid:   func(r *kra.TCCVerificationResult) bool { return r.IsValid },
+	}
 }
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/BerjisTech/kra-cli/internal"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -15,7 +19,9 @@ var configCmd = &cobra.Command{
 	Short: "Manage CLI configuration",
 	Long: `Manage KRA-CLI configuration settings.
 
-Configuration is stored in ~/.kra-cli.yaml by default.
+Configuration is stored in ~/.kra-cli.yaml by default. When a profile is
+active (--profile, KRA_PROFILE or "config profile use"), set, get and delete
+operate on that profile's settings.
 
 Available settings:
   - api_key: Your KRA GavaConnect API key
@@ -25,6 +31,22 @@ Available settings:
   - token_url: OAuth token endpoint
   - timeout: Request timeout in seconds
   - output: Default output format (table, json, csv)
+  - secret_backend: Where api_key and client_secret are kept: keyring
+    (default), file (passphrase-encrypted, for headless systems) or plain
+  - commands.<command>.<flag>: Default for a command's flag, used when the
+    flag is not given, e.g. commands.verify-pin.output or
+    commands.get-taxpayer.show_obligations
+  - cache.verify_pin_ttl, cache.get_taxpayer_ttl: How long verify-pin and
+    get-taxpayer results are reused from the local cache (e.g. 24h, 7d);
+    caching is off while unset
+  - history: Record every API call in a local database searchable with
+    "kra-cli history" (true or false; default false)
+  - notify.<name>.<field>: A notification target used with --notify, e.g.
+    notify.ops.type slack and notify.ops.url (see: kra-cli notify --help)
+  - serve.api_keys: Comma-separated API keys accepted by "kra-cli serve"
+
+Secrets are never written to the config file in plaintext unless
+secret_backend is plain; the config file only records where they are stored.
 
 Examples:
   # Set API key
@@ -49,7 +71,10 @@ Examples:
   kra-cli config delete api-key
 
   # Show config file location
-  kra-cli config path`,
+  kra-cli config path
+
+  # Manage named profiles (see: kra-cli config profile --help)
+  kra-cli config profile create sandbox --base-url https://sbx.kra.go.ke`,
 }
 
 var configSetCmd = &cobra.Command{
@@ -65,12 +90,23 @@ Available keys:
   - token-url: OAuth token endpoint
   - timeout: Request timeout in seconds
   - output: Default output format (table, json, csv)
+  - secret-backend: keyring, file or plain
+  - commands.<command>.<flag>: Default for a command's flag
+  - notify.<name>.<field>: A notification target (see: kra-cli notify --help)
+  - serve.api-keys: Comma-separated API keys accepted by "kra-cli serve"
+
+The api-key, client-secret and serve.api-keys values, and the secret and
+password of notification targets, are stored in the secret backend. The
+file backend reads its passphrase from KRA_SECRETS_PASSPHRASE or prompts for it.
 
 Examples:
   kra-cli config set api-key YOUR_API_KEY
+  kra-cli config set secret-backend file
   kra-cli config set base-url https://api.kra.go.ke/gavaconnect
   kra-cli config set output json
-  kra-cli config set timeout 60`,
+  kra-cli config set timeout 60
+  kra-cli config set commands.verify-pin.output csv
+  kra-cli config set commands.get-taxpayer.show_obligations true`,
 	Args: cobra.ExactArgs(2),
 	RunE: runConfigSet,
 }
@@ -138,36 +174,81 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 
 	// Validate key
 	validKeys := map[string]bool{
-		"api_key":       true,
-		"client_id":     true,
-		"client_secret": true,
-		"base_url":      true,
-		"token_url":     true,
-		"timeout":       true,
-		"output":        true,
+		"api_key":        true,
+		"client_id":      true,
+		"client_secret":  true,
+		"base_url":       true,
+		"token_url":      true,
+		"timeout":        true,
+		"output":         true,
+		"secret_backend": true,
+		"history":        true,
+		"serve.api_keys": true,
 	}
 
-	if !validKeys[viperKey] {
-		return fmt.Errorf("invalid configuration key: %s (valid keys: api-key, client-id, client-secret, base-url, token-url, timeout, output)", key)
+	if strings.HasPrefix(viperKey, commandsKey+".") {
+		if err := validateCommandDefault(viperKey); err != nil {
+			return err
+		}
+	} else if strings.HasPrefix(viperKey, notifyKey+".") {
+		if err := validateNotifySetting(viperKey, value); err != nil {
+			return err
+		}
+	} else if isCacheTTLKey(viperKey) {
+		if _, err := internal.ParseDuration(value); err != nil {
+			return inputErrorf("invalid %s: %w", key, err)
+		}
+	} else if !validKeys[viperKey] {
+		return inputErrorf("invalid configuration key: %s (valid keys: api-key, client-id, client-secret, base-url, token-url, timeout, output, secret-backend, cache.verify-pin-ttl, cache.get-taxpayer-ttl, history, serve.api-keys, notify.<name>.<field>)", key)
 	}
 
-	// Set the value
-	viper.Set(viperKey, value)
+	if viperKey == "history" {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return inputErrorf("invalid history setting: %s (use true or false)", value)
+		}
+	}
 
-	// Ensure config directory exists
-	home, err := os.UserHomeDir()
+	if viperKey == "secret_backend" {
+		switch value {
+		case secretBackendKeyring, secretBackendFile, secretBackendPlain:
+		default:
+			return inputErrorf("invalid secret backend: %s (use keyring, file or plain)", value)
+		}
+	}
+
+	file, configPath, err := loadConfigFile()
 	if err != nil {
-		return fmt.Errorf("could not find home directory: %w", err)
+		return err
 	}
 
-	configPath := filepath.Join(home, ".kra-cli.yaml")
+	// Settings go into the active profile when one is selected
+	target := viperKey
+	if activeProfile != "" {
+		if !file.IsSet(profilePath(activeProfile)) {
+			return inputErrorf("profile %q not found (create it with: kra-cli config profile create %s)", activeProfile, activeProfile)
+		}
+		target = profilePath(activeProfile, viperKey)
+	}
+
+	// Set the value; secrets go to the secret backend
+	location, err := storeSecret(file, target, profileName(), viperKey, value)
+	if err != nil {
+		return err
+	}
 
 	// Write config file
-	if err := viper.WriteConfigAs(configPath); err != nil {
+	if err := file.WriteConfigAs(configPath); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
-	fmt.Printf("✓ Configuration updated: %s = %s\n", key, value)
+	if isSecretKey(viperKey) {
+		value = "<stored in " + location + ">"
+	}
+	if activeProfile != "" {
+		fmt.Printf("✓ Configuration updated: %s = %s (profile %s)\n", key, value, activeProfile)
+	} else {
+		fmt.Printf("✓ Configuration updated: %s = %s\n", key, value)
+	}
 	fmt.Printf("  Config file: %s\n", configPath)
 
 	return nil
@@ -177,12 +258,20 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 	key := args[0]
 	viperKey := convertKeyToViperFormat(key)
 
-	value := viper.Get(viperKey)
+	value := setting(viperKey)
 	if value == nil {
 		fmt.Printf("%s is not set\n", key)
 		return nil
 	}
 
+	if isSecretKey(viperKey) {
+		secret, err := resolveSecret(viperKey, fmt.Sprintf("%v", value))
+		if err != nil {
+			return err
+		}
+		value = secret
+	}
+
 	fmt.Printf("%s: %v\n", key, value)
 	return nil
 }
@@ -191,36 +280,34 @@ func runConfigDelete(cmd *cobra.Command, args []string) error {
 	key := args[0]
 	viperKey := convertKeyToViperFormat(key)
 
-	// Check if key exists
-	if !viper.IsSet(viperKey) {
-		fmt.Printf("%s is not set\n", key)
-		return nil
+	file, configPath, err := loadConfigFile()
+	if err != nil {
+		return err
 	}
 
-	// Get all settings
-	settings := viper.AllSettings()
-
-	// Delete the key
-	delete(settings, viperKey)
-
-	// Clear viper
-	viper.Reset()
+	target := viperKey
+	if activeProfile != "" {
+		target = profilePath(activeProfile, viperKey)
+	}
 
-	// Re-set all settings except the deleted one
-	for k, v := range settings {
-		viper.Set(k, v)
+	// Check if key exists
+	if !file.IsSet(target) {
+		fmt.Printf("%s is not set\n", key)
+		return nil
 	}
 
-	// Write config file
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("could not find home directory: %w", err)
+	if isSecretKey(viperKey) {
+		if err := deleteSecret(profileName(), viperKey, file.Get(target)); err != nil {
+			return err
+		}
 	}
 
-	configPath := filepath.Join(home, ".kra-cli.yaml")
+	// Delete the key and rewrite the remaining settings
+	settings := file.AllSettings()
+	deleteSetting(settings, target)
 
-	if err := viper.WriteConfigAs(configPath); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	if err := writeConfigSettings(settings, configPath); err != nil {
+		return err
 	}
 
 	fmt.Printf("✓ Configuration deleted: %s\n", key)
@@ -228,29 +315,84 @@ func runConfigDelete(cmd *cobra.Command, args []string) error {
 }
 
 func runConfigView(cmd *cobra.Command, args []string) error {
-	settings := viper.AllSettings()
+	file, _, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	settings := file.AllSettings()
+	profiles, _ := settings["profiles"].(map[string]interface{})
+	delete(settings, "profiles")
 
-	if len(settings) == 0 {
+	if len(settings) == 0 && len(profiles) == 0 {
 		fmt.Println("No configuration set")
 		return nil
 	}
 
+	if activeProfile != "" {
+		fmt.Printf("Active profile: %s\n", activeProfile)
+	}
+
 	fmt.Println("Configuration:")
+	printSettings(settings)
+
+	for _, name := range profileNames() {
+		fmt.Printf("Profile %s:\n", name)
+		values, _ := profiles[name].(map[string]interface{})
+		printSettings(values)
+	}
+
+	return nil
+}
+
+// printSettings prints settings sorted by key, flattening nested sections
+// such as commands into dotted keys
+func printSettings(settings map[string]interface{}) {
+	flat := map[string]interface{}{}
+	flattenSettings(flat, "", settings)
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  %s: %s\n", key, displayValue(key, flat[key]))
+	}
+}
+
+// flattenSettings copies settings into flat with dotted keys
+func flattenSettings(flat map[string]interface{}, prefix string, settings map[string]interface{}) {
 	for key, value := range settings {
-		// Mask sensitive values
-		if key == "api_key" || key == "client_secret" {
-			valueStr := fmt.Sprintf("%v", value)
-			if len(valueStr) > 8 {
-				fmt.Printf("  %s: %s...%s\n", key, valueStr[:4], valueStr[len(valueStr)-4:])
-			} else {
-				fmt.Printf("  %s: %v\n", key, value)
-			}
-		} else {
-			fmt.Printf("  %s: %v\n", key, value)
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenSettings(flat, prefix+key+".", nested)
+			continue
 		}
+		flat[prefix+key] = value
 	}
+}
 
-	return nil
+// displayValue formats a config value for display, showing where secrets
+// are stored and masking any kept in plaintext
+func displayValue(key string, value interface{}) string {
+	valueStr := fmt.Sprintf("%v", value)
+
+	if backend, ok := secretRefBackend(valueStr); ok {
+		if store, err := openSecretStore(backend); err == nil {
+			return "<stored in " + store.Location() + ">"
+		}
+		return "<stored in " + backend + ">"
+	}
+
+	if isSecretKey(key) {
+		masked := valueStr
+		if len(valueStr) > 8 {
+			masked = valueStr[:4] + "..." + valueStr[len(valueStr)-4:]
+		}
+		return masked + " (plaintext in config file)"
+	}
+
+	return valueStr
 }
 
 func runConfigPath(cmd *cobra.Command, args []string) error {
@@ -262,12 +404,11 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show default path
-	home, err := os.UserHomeDir()
+	defaultPath, err := configFilePath()
 	if err != nil {
-		return fmt.Errorf("could not find home directory: %w", err)
+		return err
 	}
 
-	defaultPath := filepath.Join(home, ".kra-cli.yaml")
 	fmt.Printf("Config file (default): %s\n", defaultPath)
 
 	// Check if file exists
@@ -280,6 +421,95 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// configFilePath returns the config file to write: --config, the file that
+// was read, or ~/.kra-cli.yaml
+func configFilePath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find home directory: %w", err)
+	}
+	return filepath.Join(home, ".kra-cli.yaml"), nil
+}
+
+// isCacheTTLKey reports whether key is the cache TTL setting of a command
+func isCacheTTLKey(key string) bool {
+	for _, ttlKey := range cacheTTLKeys {
+		if key == ttlKey {
+			return true
+		}
+	}
+	return false
+}
+
+// dataPath returns a path under the data directory, where kra-cli keeps
+// ledgers, caches and history: $KRA_DATA_DIR or ~/.kra-cli
+func dataPath(elem ...string) (string, error) {
+	dir := os.Getenv("KRA_DATA_DIR")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not find home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".kra-cli")
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// loadConfigFile reads the config file on its own, without environment or
+// flag overrides, so it can be edited and written back
+func loadConfigFile() (*viper.Viper, string, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, "", err
+	}
+
+	file := viper.New()
+	file.SetConfigFile(path)
+	file.SetConfigType("yaml")
+
+	if _, err := os.Stat(path); err == nil {
+		if err := file.ReadInConfig(); err != nil {
+			return nil, "", fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	return file, path, nil
+}
+
+// writeConfigSettings replaces the config file at path with settings
+func writeConfigSettings(settings map[string]interface{}, path string) error {
+	file := viper.New()
+	file.SetConfigType("yaml")
+	for k, v := range settings {
+		file.Set(k, v)
+	}
+
+	if err := file.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// deleteSetting removes a possibly nested dotted key from settings
+func deleteSetting(settings map[string]interface{}, key string) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := settings[part].(map[string]interface{})
+		if !ok {
+			return
+		}
+		settings = next
+	}
+	delete(settings, parts[len(parts)-1])
+}
+
 // convertKeyToViperFormat converts hyphenated keys to underscored format
 // e.g., "api-key" -> "api_key"
 func convertKeyToViperFormat(key string) string {
@@ -294,6 +524,14 @@ func convertKeyToViperFormat(key string) string {
 		return "client_secret"
 	case "token-url":
 		return "token_url"
+	case "secret-backend":
+		return "secret_backend"
+	case "cache.verify-pin-ttl":
+		return "cache.verify_pin_ttl"
+	case "cache.get-taxpayer-ttl":
+		return "cache.get_taxpayer_ttl"
+	case "serve.api-keys":
+		return "serve.api_keys"
 	default:
 		return key
 	}
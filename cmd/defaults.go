@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// commandsKey is the config section holding per-command flag defaults, e.g.
+//
+//	commands:
+//	  verify-pin:
+//	    output: csv
+//	  get-taxpayer:
+//	    show_obligations: true
+const commandsKey = "commands"
+
+// applyCommandDefaults sets the flags of cmd that were not given on the
+// command line from its section under "commands" in the config file. Values in
+// the active profile take precedence over top-level ones.
+func applyCommandDefaults(cmd *cobra.Command) error {
+	path := commandDefaultsPath(cmd)
+	if path == "" {
+		return nil
+	}
+
+	defaults := map[string]interface{}{}
+	for key, value := range viper.GetStringMap(path) {
+		defaults[key] = value
+	}
+	if activeProfile != "" {
+		for key, value := range viper.GetStringMap(profilePath(activeProfile, path)) {
+			defaults[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := commandFlag(cmd, key)
+		if flag == nil {
+			// Nested maps are the sections of subcommands
+			if _, ok := defaults[key].(map[string]interface{}); !ok {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring %s.%s: %s has no such flag\n", path, key, cmd.CommandPath())
+			}
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+
+		for _, value := range flagValues(defaults[key]) {
+			if err := flag.Value.Set(value); err != nil {
+				return inputErrorf("invalid value %q for %s.%s: %v", value, path, key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// commandDefaultsPath returns the config key of cmd's defaults section,
+// e.g. "commands.verify-pin", or "" for the root command
+func commandDefaultsPath(cmd *cobra.Command) string {
+	var parts []string
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		parts = append([]string{c.Name()}, parts...)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return commandsKey + "." + strings.Join(parts, ".")
+}
+
+// commandFlag finds the flag named by a config key, accepting underscores for
+// hyphens (show_obligations for --show-obligations)
+func commandFlag(cmd *cobra.Command, key string) *pflag.Flag {
+	name := strings.ReplaceAll(key, "_", "-")
+	if flag := cmd.Flags().Lookup(name); flag != nil {
+		return flag
+	}
+	return cmd.InheritedFlags().Lookup(name)
+}
+
+// flagValues converts a config value into flag values; lists set a
+// repeatable flag once per element
+func flagValues(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+		return values
+	}
+	return []string{fmt.Sprintf("%v", value)}
+}
+
+// validateCommandDefault checks that a "commands.<command>.<flag>" key names
+// an existing command and flag
+func validateCommandDefault(key string) error {
+	parts := strings.Split(key, ".")
+	if len(parts) < 3 || parts[0] != commandsKey {
+		return inputErrorf("invalid command default: %s (expected commands.<command>.<flag>)", key)
+	}
+
+	cmd, rest, err := rootCmd.Find(parts[1 : len(parts)-1])
+	if err != nil || len(rest) > 0 || cmd == rootCmd {
+		return inputErrorf("invalid command default: %s (unknown command %q)", key, strings.Join(parts[1:len(parts)-1], " "))
+	}
+	if commandFlag(cmd, parts[len(parts)-1]) == nil {
+		return inputErrorf("invalid command default: %s (%s has no --%s flag)", key, cmd.CommandPath(), strings.ReplaceAll(parts[len(parts)-1], "_", "-"))
+	}
+	return nil
+}
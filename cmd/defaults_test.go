@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestApplyCommandDefaults(t *testing.T) {
+	defer viper.Reset()
+	defer func() { activeProfile = "" }()
+
+	var output string
+	var obligations, changed bool
+	parent := &cobra.Command{Use: "kra-cli"}
+	parent.PersistentFlags().StringVar(&output, "output", "table", "")
+	child := &cobra.Command{Use: "get-taxpayer", Run: func(*cobra.Command, []string) {}}
+	child.Flags().BoolVar(&obligations, "show-obligations", false, "")
+	child.Flags().BoolVar(&changed, "explicit", false, "")
+	parent.AddCommand(child)
+
+	viper.Set("commands.get-taxpayer.show_obligations", true)
+	viper.Set("commands.get-taxpayer.output", "json")
+	viper.Set("commands.get-taxpayer.explicit", true)
+	viper.Set(profilePath("sandbox", "commands.get-taxpayer.output"), "csv")
+	activeProfile = "sandbox"
+
+	if err := child.ParseFlags([]string{"--explicit=false"}); err != nil {
+		t.Fatalf("ParseFlags returned error: %v", err)
+	}
+	if err := applyCommandDefaults(child); err != nil {
+		t.Fatalf("applyCommandDefaults returned error: %v", err)
+	}
+
+	if !obligations {
+		t.Fatalf("expected show_obligations default to be applied")
+	}
+	if output != "csv" {
+		t.Fatalf("output = %q, expected profile default csv", output)
+	}
+	if changed {
+		t.Fatalf("expected explicit flag to win over the config default")
+	}
+}
+
+func TestCommandDefaultsPath(t *testing.T) {
+	parent := &cobra.Command{Use: "kra-cli"}
+	group := &cobra.Command{Use: "filings"}
+	child := &cobra.Command{Use: "list"}
+	parent.AddCommand(group)
+	group.AddCommand(child)
+
+	if got := commandDefaultsPath(child); got != "commands.filings.list" {
+		t.Fatalf("commandDefaultsPath = %q", got)
+	}
+	if got := commandDefaultsPath(parent); got != "" {
+		t.Fatalf("expected no defaults path for the root command, got %q", got)
+	}
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Show what changed between two runs",
+	Long: `Compare two sets of results saved with --output json and show what changed.
+
+Records are matched by PIN, TCC or e-slip number. Reported changes are records
+added or removed, lookups that started or stopped failing, validity changes
+(valid → invalid), status changes (active → inactive), name, taxpayer type and
+TCC expiry changes, and obligations added or removed.
+
+With the default --fail-on invalid, the command exits with code 2 when a
+record became invalid.
+
+Examples:
+  # Re-verify the vendor master each month and compare
+  kra-cli verify-pin --batch vendors.csv --output json > 2026-03.json
+  kra-cli diff 2026-02.json 2026-03.json
+
+  # Compare taxpayer details, including obligations
+  kra-cli get-taxpayer P051234567A --output json > after.json
+  kra-cli diff before.json after.json --output csv
+
+  # Or compare a batch run directly with a saved one
+  kra-cli verify-pin --batch vendors.csv --compare-to 2026-02.json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	old, err := internal.LoadResultRecords(args[0])
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+	new, err := internal.LoadResultRecords(args[1])
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+
+	changes := internal.DiffResults(old, new)
+	if err := printChanges(internal.NewOutputFormatter(outputFmt), changes, len(new)); err != nil {
+		return err
+	}
+	return diffOutcome(changes)
+}
+
+// compareWithSaved compares results with a run saved as JSON
+func compareWithSaved(path string, results interface{}) ([]internal.RecordChange, int, error) {
+	old, err := internal.LoadResultRecords(path)
+	if err != nil {
+		return nil, 0, withExitCode(exitInput, err)
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, 0, err
+	}
+	new, err := internal.ParseResultRecords(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return internal.DiffResults(old, new), len(new), nil
+}
+
+// printChanges prints the changes between two runs of compared records
+func printChanges(formatter *internal.OutputFormatter, changes []internal.RecordChange, compared int) error {
+	if len(changes) == 0 && strings.EqualFold(formatter.Format, "table") {
+		fmt.Printf("✓ No changes in %d records\n", compared)
+		return nil
+	}
+	if err := formatter.Print(changes); err != nil {
+		return err
+	}
+
+	if verbose {
+		counts := make(map[string]int)
+		keys := make(map[string]bool)
+		for _, c := range changes {
+			keys[c.Key] = true
+			if c.Field == "" {
+				counts[c.Change]++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Compared %d records: %d added, %d removed, %d changed\n",
+			compared, counts[internal.ChangeAdded], counts[internal.ChangeRemoved], len(keys)-counts[internal.ChangeAdded]-counts[internal.ChangeRemoved])
+	}
+	return nil
+}
+
+// diffOutcome returns the exit error for a set of changes according to
+// --fail-on: records that became invalid count as invalid records
+func diffOutcome(changes []internal.RecordChange) error {
+	invalid := 0
+	for _, c := range changes {
+		if c.Field == "valid" && c.New == "invalid" {
+			invalid++
+		}
+	}
+	if invalid > 0 && failOn == failOnInvalid {
+		return withExitCode(exitInvalid, fmt.Errorf("%d records became invalid", invalid))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestReportBatchCompareTo(t *testing.T) {
+	saved := filepath.Join(t.TempDir(), "last.json")
+	os.WriteFile(saved, []byte(`[
+		{"line": 2, "input": "P051234567A", "status": "invalid", "result": {"is_valid": false}},
+		{"line": 3, "input": "P059876543B", "status": "ok", "result": {"is_valid": true}}
+	]`), 0o600)
+
+	batchCompareTo = saved
+	defer func() { batchCompareTo = "" }()
+
+	type result struct {
+		Valid bool `json:"is_valid"`
+	}
+	formatter := internal.NewOutputFormatter("json")
+	formatter.Writer = io.Discard
+
+	// A record that was already invalid is not a change
+	unchanged := []internal.BatchRecord[*result]{
+		{Line: 2, Input: "P051234567A", Status: internal.StatusInvalid, Result: &result{}},
+		{Line: 3, Input: "P059876543B", Status: internal.StatusOK, Result: &result{Valid: true}},
+	}
+	if err := reportBatch(context.Background(), formatter, unchanged); err != nil {
+		t.Errorf("expected no error without changes, got %v", err)
+	}
+
+	became := []internal.BatchRecord[*result]{
+		{Line: 2, Input: "P051234567A", Status: internal.StatusInvalid, Result: &result{}},
+		{Line: 3, Input: "P059876543B", Status: internal.StatusInvalid, Result: &result{}},
+	}
+	if err := reportBatch(context.Background(), formatter, became); exitCode(err) != exitInvalid {
+		t.Errorf("expected exit %d when a record became invalid, got %v", exitInvalid, err)
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+// Process exit codes
+const (
+	exitOK       = 0 // every record was valid / the return was accepted
+	exitFailure  = 1 // unclassified failure
+	exitInvalid  = 2 // at least one record was invalid
+	exitAuth     = 3 // authentication failed or is not configured
+	exitNetwork  = 4 // network error or timeout
+	exitInput    = 5 // invalid arguments, flags or input file
+	exitRejected = 6 // a NIL return was rejected
+)
+
+// Values accepted by --fail-on
+const (
+	failOnInvalid = "invalid" // exit non-zero for invalid or failed records
+	failOnError   = "error"   // exit non-zero only for failed records
+	failOnNever   = "never"   // exit zero whatever the records say
+)
+
+var (
+	failOn string
+
+	// commandStarted is set once argument and flag validation has passed, so
+	// that errors returned earlier are reported as input errors
+	commandStarted bool
+)
+
+// exitError is an error that carries a specific process exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode attaches an exit code to err
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// inputErrorf formats an error caused by invalid user input
+func inputErrorf(format string, args ...interface{}) error {
+	return withExitCode(exitInput, fmt.Errorf(format, args...))
+}
+
+// exitCode maps an error to the documented process exit code
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if !commandStarted {
+		return exitInput
+	}
+	var tokenErr *internal.TokenAuthError
+	if errors.As(err, &tokenErr) {
+		return exitAuth
+	}
+	if isNetworkError(err) {
+		return exitNetwork
+	}
+	if isAuthError(err) {
+		return exitAuth
+	}
+	return exitFailure
+}
+
+// isNetworkError reports whether err is a timeout or a failure to connect or
+// resolve a host. Other errors from an HTTP client, such as a refused token
+// wrapped in a *url.Error, are not. The SDK reaches GavaConnect through the
+// client relays, which answer 502 or 504 when it cannot be reached or times
+// out.
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return true
+	}
+	return gatewayStatusCode.MatchString(strings.ToLower(err.Error()))
+}
+
+// gatewayStatusCode matches a 502 or 504 given as a status code, like
+// authStatusCode
+var gatewayStatusCode = regexp.MustCompile(`\b(?:http|status|status code|code)[\s:=]*(?:502|504)\b`)
+
+// authStatusCode matches a 401 or 403 given as a status code, e.g. "HTTP 401" or
+// "status code: 403", but not those digits inside a PIN or a byte count
+var authStatusCode = regexp.MustCompile(`\b(?:http|status|status code|code)[\s:=]*(?:401|403)\b`)
+
+// isAuthError reports whether err looks like an authentication failure. The
+// SDK does not export typed auth errors, so this matches on the message.
+func isAuthError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"unauthorized", "authentication", "forbidden", "invalid api key", "invalid_client"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return authStatusCode.MatchString(msg)
+}
+
+// validateFailOn checks the --fail-on flag value
+func validateFailOn() error {
+	switch failOn {
+	case failOnInvalid, failOnError, failOnNever:
+		return nil
+	default:
+		return inputErrorf("invalid --fail-on value %q (use invalid, error or never)", failOn)
+	}
+}
+
+// validityOutcome returns the exit error for a single checked item
+func validityOutcome(valid bool, what string) error {
+	if !valid && failOn == failOnInvalid {
+		return withExitCode(exitInvalid, fmt.Errorf("%s is not valid", what))
+	}
+	return nil
+}
+
+// batchOutcome returns the exit error for a set of batch records according to
+// --fail-on. Failed rows take precedence over invalid ones and use the exit
+// code of the first failure.
+func batchOutcome[T any](records []internal.BatchRecord[T]) error {
+	var invalid, failed int
+	var first *internal.BatchRecord[T]
+
+	for i := range records {
+		switch records[i].Status {
+		case internal.StatusInvalid:
+			invalid++
+		case internal.StatusError:
+			failed++
+			if first == nil {
+				first = &records[i]
+			}
+		}
+	}
+
+	if failed > 0 && failOn != failOnNever {
+		err := first.Err
+		if err == nil {
+			err = errors.New(first.Error)
+		}
+		return withExitCode(exitCode(err), fmt.Errorf("%d of %d rows failed (first on line %d: %s)", failed, len(records), first.Line, first.Error))
+	}
+	if invalid > 0 && failOn == failOnInvalid {
+		return withExitCode(exitInvalid, fmt.Errorf("%d of %d rows are invalid", invalid, len(records)))
+	}
+	return nil
+}
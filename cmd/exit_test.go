@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestExitCode(t *testing.T) {
+	commandStarted = true
+	defer func() { commandStarted = false }()
+
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("boom"), exitFailure},
+		{fmt.Errorf("failed to verify PIN: %w", context.DeadlineExceeded), exitNetwork},
+		{errors.New("request failed: 401 Unauthorized"), exitAuth},
+		{errors.New("token request failed: HTTP 403"), exitAuth},
+		{errors.New("API error (status code: 401)"), exitAuth},
+		{errors.New("PIN P051401234X not found"), exitFailure},
+		{errors.New("e-slip 2024031403 is not valid"), exitFailure},
+		{errors.New("unexpected EOF after 403 bytes"), exitFailure},
+		{errors.New("API error (status code: 502): relay: dial tcp: lookup sbx.kra.go.ke: no such host"), exitNetwork},
+		{errors.New("HTTP 504"), exitNetwork},
+		{inputErrorf("missing PIN"), exitInput},
+		{withExitCode(exitRejected, errors.New("rejected")), exitRejected},
+		{&url.Error{Op: "Get", URL: "https://sbx.kra.go.ke", Err: &net.DNSError{Err: "no such host", Name: "sbx.kra.go.ke"}}, exitNetwork},
+		{&url.Error{Op: "Post", URL: "https://sbx.kra.go.ke", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}, exitNetwork},
+		{&url.Error{Op: "Get", URL: "https://sbx.kra.go.ke", Err: errors.New("stopped after 10 redirects")}, exitFailure},
+	}
+
+	for _, c := range cases {
+		if got := exitCode(c.err); got != c.want {
+			t.Fatalf("exitCode(%v) = %d, expected %d", c.err, got, c.want)
+		}
+	}
+}
+
+func TestExitCodeTokenRefused(t *testing.T) {
+	commandStarted = true
+	defer func() { commandStarted = false }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	transport := &internal.TokenTransport{TokenURL: server.URL + "/v1/token/generate", ClientID: "id", ClientSecret: "wrong"}
+	client := &http.Client{Transport: transport}
+	_, err := client.Get(server.URL + "/v1/token/generate")
+	if err == nil {
+		t.Fatal("expected the token request to fail")
+	}
+
+	if got := exitCode(err); got != exitAuth {
+		t.Errorf("exitCode(%v) = %d, expected %d", err, got, exitAuth)
+	}
+	if got := gatewayError(err).Code; got != internal.GatewayUpstreamAuth {
+		t.Errorf("gatewayError(%v) has code %s, expected %s", err, got, internal.GatewayUpstreamAuth)
+	}
+}
+
+func TestBatchOutcome(t *testing.T) {
+	defer func() { failOn = failOnInvalid }()
+
+	valid := []internal.BatchRecord[string]{{Status: internal.StatusOK}}
+	invalid := append(valid, internal.BatchRecord[string]{Status: internal.StatusInvalid})
+	failed := append(invalid, internal.BatchRecord[string]{Line: 4, Status: internal.StatusError, Error: "missing PIN", Err: inputErrorf("missing PIN")})
+
+	cases := []struct {
+		failOn  string
+		records []internal.BatchRecord[string]
+		want    int
+	}{
+		{failOnInvalid, valid, exitOK},
+		{failOnInvalid, invalid, exitInvalid},
+		{failOnInvalid, failed, exitInput},
+		{failOnError, invalid, exitOK},
+		{failOnError, failed, exitInput},
+		{failOnNever, failed, exitOK},
+	}
+
+	for _, c := range cases {
+		failOn = c.failOn
+		if got := exitCode(batchOutcome(c.records)); got != c.want {
+			t.Fatalf("fail-on %s with %d records: exit %d, expected %d", c.failOn, len(c.records), got, c.want)
+		}
+	}
+}
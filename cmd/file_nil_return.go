@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BerjisTech/kra-cli/internal"
 	kra "github.com/BerjisTech/kra-connect-go-sdk"
@@ -17,41 +20,222 @@ var (
 	nilReturnPeriod         string
 	nilReturnMonth          int
 	nilReturnYear           int
+	nilReturnBatchFile      string
+	nilReturnDryRun         bool
+	nilReturnYes            bool
+	nilReturnForce          bool
+	nilReturnFrom           string
+	nilReturnTo             string
+	nilReturnOnReject       string
+	nilReturnAllObligations bool
 )
 
+// --on-reject values
+const (
+	onRejectStop     = "stop"
+	onRejectContinue = "continue"
+)
+
+// periodNotFiled marks periods left unfiled after a multi-period filing stopped
+const periodNotFiled = "not filed"
+
+// periodResult is the outcome of filing one period of a --from/--to range
+type periodResult struct {
+	Period    string `json:"period"`
+	Status    string `json:"status"`
+	Reference string `json:"reference,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// obligationResult is the outcome of filing one obligation with --all-obligations
+type obligationResult struct {
+	ObligationCode string `json:"obligation_code"`
+	Description    string `json:"description"`
+	Status         string `json:"status"`
+	Reference      string `json:"reference,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// taxObligation is an active obligation read from the taxpayer's details
+type taxObligation struct {
+	Code        int
+	CodeText    string // the code as returned, kept when it is not numeric
+	Description string
+}
+
+// obligationNotSelected marks obligations left out at the selection prompt
+const obligationNotSelected = "not selected"
+
+// nilReturnPlan is one row of a batch filing as validated before submission
+type nilReturnPlan struct {
+	Line           int    `json:"line"`
+	PIN            string `json:"pin"`
+	ObligationCode string `json:"obligation_code"`
+	Period         string `json:"period"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
 var fileNilReturnCmd = &cobra.Command{
 	Use:   "file-nil-return",
 	Short: "File a NIL return for a tax obligation",
-	Long:  `File a NIL return for a tax obligation using the GavaConnect API.`,
-	RunE:  runFileNilReturn,
+	Long: `File a NIL return for a tax obligation using the GavaConnect API.
+
+With --batch, every row of a CSV file is validated first, a summary is shown
+and you are asked to confirm before anything is filed. The file needs pin and
+obligation_code columns and either a period column (YYYYMM) or month and year
+columns. Rows that fail validation are reported and not filed.
+
+Every filing is recorded in the profile's filing ledger (see: kra-cli filings).
+A return already filed for the same PIN, obligation and period is refused, or
+skipped in a batch, unless it was rejected or --force is given.
+
+With --from and --to, a return is filed for every month in the range, oldest
+first, after confirmation. The range may not reach past the current month
+or span more than 60 months. Periods already in the ledger are skipped. A
+rejected return stops the run unless --on-reject continue is given; an API
+error always stops it, leaving the remaining periods unfiled.
+
+A return that is still pending is recorded as such and must be followed up
+on iTax: GavaConnect offers no status lookup (see: kra-cli filings status).
+
+With --receipt-dir, a signed JSON receipt and a printable PDF receipt are
+written for every filing, named PIN_OBLIGATION_PERIOD.json and .pdf. Verify a
+JSON receipt with: kra-cli filings verify-receipt.
+
+With --all-obligations instead of --obligation-code, the taxpayer's active
+obligations are looked up and you choose which to file for the period (all by
+default, or every one without asking with --yes).
+
+Examples:
+  # File a single NIL return
+  kra-cli file-nil-return --pin P051234567A --obligation-code 4 --period 202401
+
+  # Catch up on every month from January 2023 to June 2024
+  kra-cli file-nil-return --pin P051234567A --obligation-code 4 --from 202301 --to 202406
+
+  # File for every active obligation of a PIN, choosing at the prompt
+  kra-cli file-nil-return --pin P051234567A --all-obligations --period 202401
+
+  # Preview a batch without filing anything
+  kra-cli file-nil-return --batch returns.csv --dry-run
+
+  # File a batch, confirming at the prompt
+  kra-cli file-nil-return --batch returns.csv
+
+  # File a batch unattended, e.g. from cron
+  kra-cli file-nil-return --batch returns.csv --yes --checkpoint returns.journal
+
+  # returns.csv:
+  # pin,obligation_code,period
+  # P051234567A,4,202401
+  # P059876543B,9,202401`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments: %v", args)
+		}
+		if nilReturnBatchFile != "" {
+			if cmd.Flags().Changed("pin") || cmd.Flags().Changed("obligation-code") || nilReturnAllObligations {
+				return fmt.Errorf("cannot use --pin, --obligation-code or --all-obligations with --batch")
+			}
+			return nil
+		}
+		if nilReturnAllObligations {
+			if cmd.Flags().Changed("obligation-code") {
+				return fmt.Errorf("cannot use --obligation-code with --all-obligations")
+			}
+			if nilReturnFrom != "" || nilReturnTo != "" {
+				return fmt.Errorf("cannot use --from or --to with --all-obligations")
+			}
+			if nilReturnPin == "" {
+				return fmt.Errorf("--all-obligations requires --pin")
+			}
+			return nil
+		}
+		if nilReturnPin == "" || !cmd.Flags().Changed("obligation-code") {
+			return fmt.Errorf("requires --pin and --obligation-code, --pin and --all-obligations, or --batch")
+		}
+		if (nilReturnFrom == "") != (nilReturnTo == "") {
+			return fmt.Errorf("--from and --to must be used together")
+		}
+		if nilReturnFrom != "" {
+			if nilReturnPeriod != "" || cmd.Flags().Changed("month") || cmd.Flags().Changed("year") {
+				return fmt.Errorf("cannot use --period, --month or --year with --from and --to")
+			}
+			if nilReturnOnReject != onRejectStop && nilReturnOnReject != onRejectContinue {
+				return fmt.Errorf("invalid --on-reject value %q (use stop or continue)", nilReturnOnReject)
+			}
+			return nil
+		}
+		if nilReturnDryRun {
+			return fmt.Errorf("--dry-run requires --batch, --from and --to, or --all-obligations")
+		}
+		return nil
+	},
+	RunE: runFileNilReturn,
 }
 
 func init() {
 	rootCmd.AddCommand(fileNilReturnCmd)
-	fileNilReturnCmd.Flags().StringVar(&nilReturnPin, "pin", "", "KRA PIN number (required)")
-	fileNilReturnCmd.Flags().IntVar(&nilReturnObligationCode, "obligation-code", 0, "Obligation code (required)")
+	fileNilReturnCmd.Flags().StringVar(&nilReturnPin, "pin", "", "KRA PIN number (required without --batch)")
+	fileNilReturnCmd.Flags().IntVar(&nilReturnObligationCode, "obligation-code", 0, "Obligation code (required without --batch)")
 	fileNilReturnCmd.Flags().StringVar(&nilReturnPeriod, "period", "", "Tax period in YYYYMM format (optional if --month and --year are provided)")
 	fileNilReturnCmd.Flags().IntVar(&nilReturnMonth, "month", 0, "Tax period month (1-12)")
 	fileNilReturnCmd.Flags().IntVar(&nilReturnYear, "year", 0, "Tax period year (e.g. 2024)")
-	fileNilReturnCmd.MarkFlagRequired("pin")
-	fileNilReturnCmd.MarkFlagRequired("obligation-code")
+	fileNilReturnCmd.Flags().StringVar(&nilReturnBatchFile, "batch", "", "CSV file of returns to file (columns: pin, obligation_code, period or month/year)")
+	fileNilReturnCmd.Flags().BoolVar(&nilReturnAllObligations, "all-obligations", false, "file for the taxpayer's active obligations instead of one --obligation-code")
+	fileNilReturnCmd.Flags().StringVar(&nilReturnFrom, "from", "", "first period of a range to file, in YYYYMM format (requires --to)")
+	fileNilReturnCmd.Flags().StringVar(&nilReturnTo, "to", "", "last period of a range to file, in YYYYMM format (requires --from)")
+	fileNilReturnCmd.Flags().StringVar(&nilReturnOnReject, "on-reject", onRejectStop, "what to do when a return in a --from/--to range is rejected: stop or continue")
+	fileNilReturnCmd.Flags().BoolVar(&nilReturnDryRun, "dry-run", false, "show what a --batch, --from/--to or --all-obligations run would file without filing")
+	fileNilReturnCmd.Flags().BoolVarP(&nilReturnYes, "yes", "y", false, "file a --batch, --from/--to range or every obligation without asking")
+	fileNilReturnCmd.Flags().BoolVar(&nilReturnForce, "force", false, "file even if the filing ledger shows the return was already filed")
+	addBatchFlags(fileNilReturnCmd)
+	addReceiptFlag(fileNilReturnCmd)
 }
 
 func runFileNilReturn(cmd *cobra.Command, args []string) error {
-	client, err := createClient()
-	if err != nil {
+	formatter := internal.NewOutputFormatter(outputFmt)
+
+	if err := prepareReceipts(); err != nil {
 		return err
 	}
-	defer client.Close()
 
-	ctx := context.Background()
-	formatter := internal.NewOutputFormatter(outputFmt)
+	if nilReturnBatchFile != "" {
+		return runFileNilReturnBatch(formatter)
+	}
+	if nilReturnFrom != "" {
+		return runFileNilReturnRange(formatter)
+	}
+	if nilReturnAllObligations {
+		return runFileNilReturnAllObligations(formatter)
+	}
 
 	month, year, err := resolvePeriod(nilReturnPeriod, nilReturnMonth, nilReturnYear)
 	if err != nil {
 		return err
 	}
 
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	key := internal.FilingKey(nilReturnPin, nilReturnObligationCode, year, month)
+	if reason, filed := duplicateFiling(ledger, key); filed && !nilReturnForce {
+		return inputErrorf("%s %s", key, reason)
+	}
+
+	client, err := createClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	if verbose {
 		fmt.Fprintf(os.Stderr, "Filing NIL return...\n")
 		fmt.Fprintf(os.Stderr, "  PIN: %s\n", nilReturnPin)
@@ -66,9 +250,14 @@ func runFileNilReturn(cmd *cobra.Command, args []string) error {
 		Year:           year,
 	}
 
-	result, err := client.FileNILReturn(ctx, request)
-	if err != nil {
-		return fmt.Errorf("failed to file NIL return: %w", err)
+	// Another process may have filed it since the check above
+	result, _, recordErr := fileOnce(ctx, ledger, historyCall(nilReturnSpec(client)), request, nilReturnForce)
+	var settled *rowError
+	if errors.As(recordErr, &settled) {
+		return inputErrorf("%s %s", key, recordErr)
+	}
+	if result == nil {
+		return fmt.Errorf("failed to file NIL return: %w", recordErr)
 	}
 
 	if verbose {
@@ -81,31 +270,595 @@ func runFileNilReturn(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return formatter.Print(result)
+	if err := formatter.Print(result); err != nil {
+		return err
+	}
+	if recordErr != nil {
+		return recordErr
+	}
+
+	if result.IsRejected() && failOn == failOnInvalid {
+		return withExitCode(exitRejected, fmt.Errorf("NIL return was rejected"))
+	}
+
+	return nil
+}
+
+func runFileNilReturnBatch(formatter *internal.OutputFormatter) error {
+	input, err := internal.ReadCSVInput(nilReturnBatchFile)
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+
+	inputs, plan, err := parseNilReturnRows(input)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	skipFiledReturns(inputs, plan, ledger)
+
+	var ready, skipped int
+	for _, p := range plan {
+		switch p.Status {
+		case "ready":
+			ready++
+		case internal.StatusSkipped:
+			skipped++
+		}
+	}
+	invalid := len(plan) - ready - skipped
+
+	if nilReturnDryRun {
+		if err := formatter.Print(plan); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Dry run: %d returns would be filed, %d skipped as already filed, %d rows have errors\n", ready, skipped, invalid)
+		if invalid > 0 && failOn != failOnNever {
+			return inputErrorf("%d of %d rows have errors", invalid, len(plan))
+		}
+		return nil
+	}
+
+	if ready == 0 && invalid == 0 {
+		fmt.Fprintf(os.Stderr, "All %d returns are already filed; nothing to do\n", skipped)
+		return nil
+	}
+	if ready == 0 {
+		return inputErrorf("no valid rows to file (see: kra-cli file-nil-return --batch %s --dry-run)", nilReturnBatchFile)
+	}
+
+	if !nilReturnYes {
+		fmt.Fprintf(os.Stderr, "About to file %d NIL returns from %s", ready, nilReturnBatchFile)
+		if invalid > 0 {
+			fmt.Fprintf(os.Stderr, " (%d rows with errors will be skipped; see --dry-run)", invalid)
+		}
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, " (%d already filed will be skipped)", skipped)
+		}
+		fmt.Fprintln(os.Stderr)
+
+		ok, err := confirm("File these returns?", "--yes")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return withExitCode(exitFailure, fmt.Errorf("aborted; nothing was filed"))
+		}
+	}
+
+	client, err := createClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	spec := nilReturnSpec(client)
+	file := spec.Call
+	spec.Call = func(ctx context.Context, r *kra.NILReturnRequest) (*kra.NILReturnResult, error) {
+		result, _, err := fileOnce(ctx, ledger, file, r, nilReturnForce)
+		if result != nil && err != nil {
+			// The return is filed either way; failing the row would invite filing it again
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+			err = nil
+		}
+		return result, err
+	}
+
+	records, err := runBatch(ctx, spec, input, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to file NIL returns: %w", err)
+	}
+
+	if err := formatter.Print(records); err != nil {
+		return err
+	}
+
+	// Rejected returns have their own exit code
+	err = batchOutcome(records)
+	var exitErr *exitError
+	if errors.As(err, &exitErr) && exitErr.code == exitInvalid {
+		return withExitCode(exitRejected, fmt.Errorf("%s (rejected)", exitErr.err))
+	}
+	return err
+}
+
+// runFileNilReturnRange files one return per month from --from to --to, in
+// chronological order
+func runFileNilReturnRange(formatter *internal.OutputFormatter) error {
+	periods, err := expandPeriods(nilReturnFrom, nilReturnTo, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	results := make([]periodResult, len(periods))
+	ready := 0
+	for i, p := range periods {
+		results[i] = periodResult{Period: fmt.Sprintf("%04d%02d", p.Year, p.Month), Status: "ready"}
+		key := internal.FilingKey(nilReturnPin, nilReturnObligationCode, p.Year, p.Month)
+		if reason, filed := duplicateFiling(ledger, key); filed && !nilReturnForce {
+			results[i].Status = internal.StatusSkipped
+			results[i].Message = reason
+			continue
+		}
+		ready++
+	}
+
+	if nilReturnDryRun {
+		if err := formatter.Print(results); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Dry run: %d returns would be filed, %d skipped as already filed\n", ready, len(results)-ready)
+		return nil
+	}
+
+	if ready == 0 {
+		fmt.Fprintf(os.Stderr, "All %d periods are already filed; nothing to do\n", len(results))
+		return nil
+	}
+
+	if !nilReturnYes {
+		fmt.Fprintf(os.Stderr, "About to file %d NIL returns for %s, obligation %d, %s to %s",
+			ready, nilReturnPin, nilReturnObligationCode, nilReturnFrom, nilReturnTo)
+		if skipped := len(results) - ready; skipped > 0 {
+			fmt.Fprintf(os.Stderr, " (%d already filed will be skipped)", skipped)
+		}
+		fmt.Fprintln(os.Stderr)
+
+		ok, err := confirm("File these returns?", "--yes")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return withExitCode(exitFailure, fmt.Errorf("aborted; nothing was filed"))
+		}
+	}
+
+	client, err := createClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	var rejected int
+	var stopErr error
+	for i, p := range periods {
+		if results[i].Status == internal.StatusSkipped {
+			continue
+		}
+		if stopErr != nil {
+			results[i].Status = periodNotFiled
+			continue
+		}
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Filing %s...\n", results[i].Period)
+		}
+
+		request := &kra.NILReturnRequest{
+			PINNumber:      nilReturnPin,
+			ObligationCode: nilReturnObligationCode,
+			Month:          p.Month,
+			Year:           p.Year,
+		}
+		result, filing, err := fileOnce(ctx, ledger, historyCall(nilReturnSpec(client)), request, nilReturnForce)
+		var settled *rowError
+		if errors.As(err, &settled) {
+			results[i].Status = settled.status
+			results[i].Message = settled.reason
+			continue
+		}
+		if result == nil {
+			results[i].Status = internal.StatusError
+			results[i].Message = err.Error()
+			stopErr = fmt.Errorf("failed to file NIL return for %s: %w", results[i].Period, err)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+		results[i].Status = filing.Status
+		results[i].Reference = filing.Reference
+		results[i].Message = filing.Message
+
+		if filing.Status == internal.FilingRejected {
+			rejected++
+			if nilReturnOnReject == onRejectStop {
+				stopErr = withExitCode(exitRejected, fmt.Errorf("NIL return for %s was rejected; stopped (use --on-reject continue to file the remaining periods)", results[i].Period))
+			}
+		}
+	}
+
+	if err := formatter.Print(results); err != nil {
+		return err
+	}
+
+	switch {
+	case stopErr != nil && exitCode(stopErr) != exitRejected:
+		if failOn != failOnNever {
+			return stopErr
+		}
+	case rejected > 0 && failOn == failOnInvalid:
+		if stopErr != nil {
+			return stopErr
+		}
+		return withExitCode(exitRejected, fmt.Errorf("%d of %d NIL returns were rejected", rejected, len(results)))
+	}
+	return nil
+}
+
+// runFileNilReturnAllObligations files the period's return for the active
+// obligations of --pin the user selects
+func runFileNilReturnAllObligations(formatter *internal.OutputFormatter) error {
+	month, year, err := resolvePeriod(nilReturnPeriod, nilReturnMonth, nilReturnYear)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	client, err := createClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Looking up obligations for PIN: %s\n", nilReturnPin)
+	}
+
+	details, err := historyCall(taxpayerSpec(client))(ctx, nilReturnPin)
+	if err != nil {
+		return fmt.Errorf("failed to get taxpayer details: %w", err)
+	}
+
+	obligations := activeObligations(details.Obligations)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "  %d of %d obligations are active\n", len(obligations), len(details.Obligations))
+	}
+	if len(obligations) == 0 {
+		return inputErrorf("no active obligations found for %s", nilReturnPin)
+	}
+
+	results := make([]obligationResult, len(obligations))
+	ready := make([]int, 0, len(obligations))
+	for i, o := range obligations {
+		results[i] = obligationResult{ObligationCode: o.CodeText, Description: o.Description, Status: "ready"}
+		if o.Code == 0 {
+			results[i].Status = internal.StatusError
+			results[i].Message = "obligation code is not numeric"
+			continue
+		}
+		key := internal.FilingKey(nilReturnPin, o.Code, year, month)
+		if reason, filed := duplicateFiling(ledger, key); filed && !nilReturnForce {
+			results[i].Status = internal.StatusSkipped
+			results[i].Message = reason
+			continue
+		}
+		ready = append(ready, i)
+	}
+
+	if nilReturnDryRun {
+		if err := formatter.Print(results); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Dry run: %d returns would be filed for %04d%02d\n", len(ready), year, month)
+		return nil
+	}
+
+	if len(ready) == 0 {
+		fmt.Fprintf(os.Stderr, "No obligations left to file for %04d%02d\n", year, month)
+		return formatter.Print(results)
+	}
+
+	if !nilReturnYes {
+		fmt.Fprintf(os.Stderr, "Active obligations of %s to file for %04d%02d:\n", nilReturnPin, year, month)
+		for n, i := range ready {
+			fmt.Fprintf(os.Stderr, "  %d. %s %s\n", n+1, results[i].ObligationCode, results[i].Description)
+		}
+
+		picked, err := choose("Obligations to file", len(ready), "--yes")
+		if err != nil {
+			return err
+		}
+		if len(picked) == 0 {
+			return withExitCode(exitFailure, fmt.Errorf("no obligations selected; nothing was filed"))
+		}
+
+		selected := make([]int, len(picked))
+		for n, p := range picked {
+			selected[n] = ready[p]
+		}
+		for _, i := range ready {
+			results[i].Status = obligationNotSelected
+		}
+		ready = selected
+	}
+
+	var rejected, failed int
+	var firstErr error
+	for _, i := range ready {
+		request := &kra.NILReturnRequest{
+			PINNumber:      nilReturnPin,
+			ObligationCode: obligations[i].Code,
+			Month:          month,
+			Year:           year,
+		}
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Filing obligation %d...\n", request.ObligationCode)
+		}
+
+		result, filing, err := fileOnce(ctx, ledger, historyCall(nilReturnSpec(client)), request, nilReturnForce)
+		var settled *rowError
+		if errors.As(err, &settled) {
+			results[i].Status = settled.status
+			results[i].Message = settled.reason
+			continue
+		}
+		if result == nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("filing interrupted: %w", ctx.Err())
+			}
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			results[i].Status = internal.StatusError
+			results[i].Message = err.Error()
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+		results[i].Status = filing.Status
+		results[i].Reference = filing.Reference
+		results[i].Message = filing.Message
+		if filing.Status == internal.FilingRejected {
+			rejected++
+		}
+	}
+
+	if err := formatter.Print(results); err != nil {
+		return err
+	}
+
+	if failed > 0 && failOn != failOnNever {
+		return withExitCode(exitCode(firstErr), fmt.Errorf("%d of %d NIL returns failed: %w", failed, len(ready), firstErr))
+	}
+	if rejected > 0 && failOn == failOnInvalid {
+		return withExitCode(exitRejected, fmt.Errorf("%d of %d NIL returns were rejected", rejected, len(ready)))
+	}
+	return nil
+}
+
+// activeObligations reads the code and description of every active
+// obligation. Obligations without a status are taken to be active.
+func activeObligations[T any](obligations []T) []taxObligation {
+	active := make([]taxObligation, 0, len(obligations))
+	for i := range obligations {
+		o := &obligations[i]
+		status := internal.LookupField(o, "status", "obligation_status")
+		if status != "" && !strings.EqualFold(status, "active") {
+			continue
+		}
+
+		code := internal.LookupField(o, "obligation_code", "code", "obligation_id")
+		n, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil || n <= 0 {
+			n = 0
+		}
+		active = append(active, taxObligation{
+			Code:        n,
+			CodeText:    code,
+			Description: internal.LookupField(o, "description", "obligation_name", "name"),
+		})
+	}
+	return active
+}
+
+// taxPeriod is one month of a --from/--to range
+type taxPeriod struct {
+	Month int
+	Year  int
+}
+
+// maxRangePeriods is the most months a single --from/--to range may file
+const maxRangePeriods = 60
+
+// expandPeriods returns every month from one YYYYMM period to another,
+// inclusive and oldest first. Ranges reaching past the month of now, or
+// longer than maxRangePeriods, are refused.
+func expandPeriods(from, to string, now time.Time) ([]taxPeriod, error) {
+	fromMonth, fromYear, err := resolvePeriod(from, 0, 0)
+	if err != nil {
+		return nil, inputErrorf("invalid --from %q: use YYYYMM format", from)
+	}
+	toMonth, toYear, err := resolvePeriod(to, 0, 0)
+	if err != nil {
+		return nil, inputErrorf("invalid --to %q: use YYYYMM format", to)
+	}
+
+	first := fromYear*12 + fromMonth - 1
+	last := toYear*12 + toMonth - 1
+	if last < first {
+		return nil, inputErrorf("--to %s is before --from %s", to, from)
+	}
+	if current := now.Year()*12 + int(now.Month()) - 1; last > current {
+		return nil, inputErrorf("--to %s is in the future", to)
+	}
+	if n := last - first + 1; n > maxRangePeriods {
+		return nil, inputErrorf("--from %s to --to %s spans %d months; file at most %d at a time", from, to, n, maxRangePeriods)
+	}
+
+	periods := make([]taxPeriod, 0, last-first+1)
+	for m := first; m <= last; m++ {
+		periods = append(periods, taxPeriod{Month: m%12 + 1, Year: m / 12})
+	}
+	return periods, nil
+}
+
+// parseNilReturnRows validates every row of a NIL return batch file and
+// describes the resulting filing plan
+func parseNilReturnRows(input *internal.CSVInput) ([]batchInput[*kra.NILReturnRequest], []nilReturnPlan, error) {
+	pinCol := input.Column("pin")
+	obligationCol := input.Column("obligation_code", "obligation")
+	periodCol := input.Column("period")
+	monthCol := input.Column("month")
+	yearCol := input.Column("year")
+
+	if pinCol == -1 || obligationCol == -1 {
+		return nil, nil, inputErrorf("CSV file must have 'pin' and 'obligation_code' columns")
+	}
+	if periodCol == -1 && (monthCol == -1 || yearCol == -1) {
+		return nil, nil, inputErrorf("CSV file must have a 'period' column or 'month' and 'year' columns")
+	}
+	if len(input.Rows) == 0 {
+		return nil, nil, inputErrorf("no returns found in CSV file")
+	}
+
+	inputs := parseBatchRows(input, func(row internal.InputRow) (*kra.NILReturnRequest, error) {
+		request := &kra.NILReturnRequest{PINNumber: row.Value(pinCol)}
+		if request.PINNumber == "" {
+			return request, inputErrorf("missing PIN")
+		}
+
+		code, err := strconv.Atoi(row.Value(obligationCol))
+		if err != nil {
+			return request, inputErrorf("invalid obligation code %q", row.Value(obligationCol))
+		}
+		request.ObligationCode = code
+
+		var period string
+		var month, year int
+		if periodCol != -1 {
+			period = row.Value(periodCol)
+		}
+		if period == "" && monthCol != -1 && yearCol != -1 {
+			month, _ = strconv.Atoi(row.Value(monthCol))
+			year, _ = strconv.Atoi(row.Value(yearCol))
+		}
+
+		request.Month, request.Year, err = resolvePeriod(period, month, year)
+		return request, err
+	})
+
+	plan := make([]nilReturnPlan, len(inputs))
+	for i, in := range inputs {
+		row := in.Row
+		plan[i] = nilReturnPlan{
+			Line:           row.Line,
+			PIN:            row.Value(pinCol),
+			ObligationCode: row.Value(obligationCol),
+			Status:         "ready",
+		}
+		if in.Err != nil {
+			plan[i].Status = internal.StatusError
+			plan[i].Error = in.Err.Error()
+			if periodCol != -1 {
+				plan[i].Period = row.Value(periodCol)
+			}
+			continue
+		}
+		plan[i].Period = fmt.Sprintf("%04d%02d", in.Value.Year, in.Value.Month)
+	}
+
+	return inputs, plan, nil
+}
+
+// skipFiledReturns marks rows that repeat an earlier row of the batch, or a
+// return the ledger shows as already filed, as skipped
+func skipFiledReturns(inputs []batchInput[*kra.NILReturnRequest], plan []nilReturnPlan, ledger *internal.Ledger) {
+	seen := make(map[string]int)
+	for i := range inputs {
+		if inputs[i].Err != nil {
+			continue
+		}
+		r := inputs[i].Value
+		key := internal.FilingKey(r.PINNumber, r.ObligationCode, r.Year, r.Month)
+
+		if line, ok := seen[key]; ok {
+			inputs[i].Err = skipInputf("duplicate of line %d", line)
+		} else if reason, filed := duplicateFiling(ledger, key); filed && !nilReturnForce {
+			inputs[i].Err = skipInputf("%s", reason)
+		}
+		if _, ok := seen[key]; !ok {
+			seen[key] = inputs[i].Row.Line
+		}
+
+		if inputs[i].Err != nil {
+			plan[i].Status = internal.StatusSkipped
+			plan[i].Error = inputs[i].Err.Error()
+		}
+	}
 }
 
 func resolvePeriod(period string, month, year int) (int, int, error) {
 	if period != "" {
 		if len(period) != 6 {
-			return 0, 0, fmt.Errorf("--period must be in YYYYMM format")
+			return 0, 0, inputErrorf("--period must be in YYYYMM format")
 		}
 
 		pYear, err := strconv.Atoi(period[:4])
 		if err != nil {
-			return 0, 0, fmt.Errorf("invalid period year: %w", err)
+			return 0, 0, inputErrorf("invalid period year: %w", err)
 		}
 		pMonth, err := strconv.Atoi(period[4:])
 		if err != nil || pMonth < 1 || pMonth > 12 {
-			return 0, 0, fmt.Errorf("invalid period month")
+			return 0, 0, inputErrorf("invalid period month")
 		}
 		return pMonth, pYear, nil
 	}
 
 	if month < 1 || month > 12 {
-		return 0, 0, fmt.Errorf("month must be between 1 and 12 or provide --period")
+		return 0, 0, inputErrorf("month must be between 1 and 12 or provide --period")
 	}
 	if year < 2000 {
-		return 0, 0, fmt.Errorf("year must be >= 2000 or provide --period")
+		return 0, 0, inputErrorf("year must be >= 2000 or provide --period")
 	}
 
 	return month, year, nil
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestParseNilReturnRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "returns.csv")
+	csv := "pin,obligation_code,month,year\nP051234567A,4,1,2024\nP059876543B,9,13,2024\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	input, err := internal.ReadCSVInput(path)
+	if err != nil {
+		t.Fatalf("ReadCSVInput returned error: %v", err)
+	}
+
+	inputs, plan, err := parseNilReturnRows(input)
+	if err != nil {
+		t.Fatalf("parseNilReturnRows returned error: %v", err)
+	}
+
+	if inputs[0].Err != nil || inputs[0].Value.Month != 1 || inputs[0].Value.Year != 2024 || inputs[0].Value.ObligationCode != 4 {
+		t.Fatalf("unexpected first row: %+v, %v", inputs[0].Value, inputs[0].Err)
+	}
+	if plan[0].Status != "ready" || plan[0].Period != "202401" {
+		t.Fatalf("unexpected plan for first row: %+v", plan[0])
+	}
+
+	if inputs[1].Err == nil || plan[1].Status != internal.StatusError || plan[1].Line != 3 {
+		t.Fatalf("expected second row to fail validation, got plan %+v", plan[1])
+	}
+}
+
+func TestSkipFiledReturns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "returns.csv")
+	csv := "pin,obligation_code,period\nP051234567A,4,202401\nP051234567A,4,202402\nP051234567A,4,202401\nP059876543B,9,202401\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	input, err := internal.ReadCSVInput(path)
+	if err != nil {
+		t.Fatalf("ReadCSVInput returned error: %v", err)
+	}
+	inputs, plan, err := parseNilReturnRows(input)
+	if err != nil {
+		t.Fatalf("parseNilReturnRows returned error: %v", err)
+	}
+
+	ledger, err := internal.OpenLedger(filepath.Join(dir, "ledger.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	defer ledger.Close()
+
+	filed := []internal.Filing{
+		{Key: "P051234567A/4/202402", Status: internal.FilingAccepted, Reference: "ACK-1"},
+		{Key: "P059876543B/9/202401", Status: internal.FilingRejected},
+	}
+	for _, f := range filed {
+		if err := ledger.Record(f); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	skipFiledReturns(inputs, plan, ledger)
+
+	want := []string{"ready", internal.StatusSkipped, internal.StatusSkipped, "ready"}
+	for i, status := range want {
+		if plan[i].Status != status {
+			t.Errorf("row %d: expected status %s, got %+v", i, status, plan[i])
+		}
+	}
+	if plan[2].Error != "duplicate of line 2" {
+		t.Errorf("unexpected duplicate reason: %q", plan[2].Error)
+	}
+}
+
+func TestExpandPeriods(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	periods, err := expandPeriods("202311", "202402", now)
+	if err != nil {
+		t.Fatalf("expandPeriods returned error: %v", err)
+	}
+
+	want := []taxPeriod{{11, 2023}, {12, 2023}, {1, 2024}, {2, 2024}}
+	if len(periods) != len(want) {
+		t.Fatalf("expected %d periods, got %v", len(want), periods)
+	}
+	for i := range want {
+		if periods[i] != want[i] {
+			t.Errorf("period %d: expected %v, got %v", i, want[i], periods[i])
+		}
+	}
+
+	if _, err := expandPeriods("202402", "202311", now); err == nil {
+		t.Error("expected an error when --to is before --from")
+	}
+	if _, err := expandPeriods("2024-01", "202402", now); err == nil {
+		t.Error("expected an error for a malformed --from")
+	}
+	if _, err := expandPeriods("202405", "202407", now); err == nil {
+		t.Error("expected an error for a range reaching into the future")
+	}
+	if _, err := expandPeriods("202406", "202406", now); err != nil {
+		t.Errorf("expected the current month to be accepted, got %v", err)
+	}
+	if _, err := expandPeriods("201901", "202406", now); err == nil {
+		t.Error("expected an error for a range longer than the limit")
+	}
+	if periods, err := expandPeriods("201907", "202406", now); err != nil || len(periods) != maxRangePeriods {
+		t.Errorf("expected a range of %d months to be accepted, got %d, %v", maxRangePeriods, len(periods), err)
+	}
+}
+
+func TestActiveObligations(t *testing.T) {
+	obligations := []map[string]interface{}{
+		{"obligation_code": "4", "description": "Income Tax - Resident Individual", "status": "Active"},
+		{"obligation_code": "7", "description": "Income Tax - PAYE", "status": "Dormant"},
+		{"obligationCode": 9, "description": "VAT"},
+		{"obligation_code": "ITR", "description": "Unknown"},
+	}
+
+	active := activeObligations(obligations)
+	if len(active) != 3 {
+		t.Fatalf("expected 3 active obligations, got %+v", active)
+	}
+	if active[0].Code != 4 || active[0].Description != "Income Tax - Resident Individual" {
+		t.Errorf("unexpected first obligation: %+v", active[0])
+	}
+	if active[1].Code != 9 {
+		t.Errorf("expected obligation without status to be active, got %+v", active[1])
+	}
+	if active[2].Code != 0 || active[2].CodeText != "ITR" {
+		t.Errorf("expected non-numeric code to be kept as text, got %+v", active[2])
+	}
+}
@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	kra "github.com/BerjisTech/kra-connect-go-sdk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	filingsPIN        string
+	filingsPeriod     string
+	filingsStatus     string
+	filingsExportFile string
+	filingsHistory    bool
+)
+
+var filingsCmd = &cobra.Command{
+	Use:   "filings",
+	Short: "Inspect the NIL return filing ledger",
+	Long: `Inspect the local ledger of NIL returns filed with file-nil-return.
+
+Every filing is recorded per profile with its PIN, obligation, period,
+status and acknowledgment reference. file-nil-return uses the ledger to refuse
+filing the same PIN, obligation and period twice unless --force is given;
+rejected returns can be filed again.
+
+Examples:
+  # List filings, optionally filtered
+  kra-cli filings list
+  kra-cli filings list --pin P051234567A --period 202401
+
+  # Show one filing by reference or by PIN/obligation/period
+  kra-cli filings show NIL-2024-000001
+  kra-cli filings show P051234567A/4/202401
+
+  # Show the status recorded for a return
+  kra-cli filings status NIL-2024-000001
+
+  # Export the audit trail
+  kra-cli filings export --file filings.csv
+  kra-cli filings export --history --output json > audit.json`,
+}
+
+var filingsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded filings",
+	Args:  cobra.NoArgs,
+	RunE:  runFilingsList,
+}
+
+var filingsShowCmd = &cobra.Command{
+	Use:   "show <reference|pin/obligation/period>",
+	Short: "Show a recorded filing",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runFilingsShow,
+}
+
+var filingsStatusCmd = &cobra.Command{
+	Use:   "status <reference|pin/obligation/period>",
+	Short: "Show the status recorded for a filing",
+	Long: `Show the status of a return as recorded in the filing ledger.
+
+This is the status GavaConnect answered when the return was filed, not its
+current state: GavaConnect offers no NIL return status lookup. A pending
+return must be followed up on iTax. Exits with code 6 when the recorded
+status is rejected.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFilingsStatus,
+}
+
+var filingsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the filing ledger as CSV or JSON",
+	Long: `Export the filing ledger as CSV (default) or JSON (--output json).
+
+By default the current state of each filing is exported; --history exports
+every recorded submission and status change.`,
+	Args: cobra.NoArgs,
+	RunE: runFilingsExport,
+}
+
+func init() {
+	rootCmd.AddCommand(filingsCmd)
+	filingsCmd.AddCommand(filingsListCmd)
+	filingsCmd.AddCommand(filingsShowCmd)
+	filingsCmd.AddCommand(filingsStatusCmd)
+	filingsCmd.AddCommand(filingsExportCmd)
+
+	filingsListCmd.Flags().StringVar(&filingsPIN, "pin", "", "only filings for this PIN")
+	filingsListCmd.Flags().StringVar(&filingsPeriod, "period", "", "only filings for this period (YYYYMM)")
+	filingsListCmd.Flags().StringVar(&filingsStatus, "status", "", "only filings with this status: accepted, pending, rejected")
+
+	filingsExportCmd.Flags().StringVar(&filingsExportFile, "file", "", "write to this file instead of stdout")
+	filingsExportCmd.Flags().BoolVar(&filingsHistory, "history", false, "export every recorded submission, not just the latest state")
+}
+
+func runFilingsList(cmd *cobra.Command, args []string) error {
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	filings := make([]internal.Filing, 0)
+	for _, filing := range ledger.List() {
+		if filingsPIN != "" && !strings.EqualFold(filing.PIN, filingsPIN) {
+			continue
+		}
+		if filingsPeriod != "" && filing.Period != filingsPeriod {
+			continue
+		}
+		if filingsStatus != "" && !strings.EqualFold(filing.Status, filingsStatus) {
+			continue
+		}
+		filings = append(filings, filing)
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	return formatter.Print(filings)
+}
+
+func runFilingsShow(cmd *cobra.Command, args []string) error {
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	filing, ok := findFiling(ledger, args[0])
+	if !ok {
+		return inputErrorf("no filing %q in the ledger for profile %s", args[0], profileName())
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(filing); err != nil {
+		return err
+	}
+
+	// The table omits the raw response; show it below
+	if strings.EqualFold(outputFmt, "table") && len(filing.Result) > 0 {
+		var pretty interface{}
+		if json.Unmarshal(filing.Result, &pretty) == nil {
+			raw, _ := json.MarshalIndent(pretty, "", "  ")
+			fmt.Printf("\nResponse:\n%s\n", raw)
+		}
+	}
+	return nil
+}
+
+func runFilingsStatus(cmd *cobra.Command, args []string) error {
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	filing, ok := findFiling(ledger, args[0])
+	if !ok {
+		return inputErrorf("no filing %q in the ledger for profile %s", args[0], profileName())
+	}
+
+	fmt.Fprintf(os.Stderr, "Status as recorded on %s; GavaConnect offers no status lookup\n", filing.UpdatedAt.Local().Format("2006-01-02 15:04"))
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(filing); err != nil {
+		return err
+	}
+
+	if filing.Status == internal.FilingRejected && failOn == failOnInvalid {
+		return withExitCode(exitRejected, fmt.Errorf("NIL return was rejected"))
+	}
+	return nil
+}
+
+func runFilingsExport(cmd *cobra.Command, args []string) error {
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	filings := ledger.List()
+	if filingsHistory {
+		filings = ledger.History()
+	}
+
+	format := outputFmt
+	if strings.EqualFold(format, "table") {
+		format = "csv"
+	}
+	formatter := internal.NewOutputFormatter(format)
+
+	if filingsExportFile == "" {
+		return formatter.Print(filings)
+	}
+
+	file, err := os.Create(filingsExportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	formatter.Writer = file
+	if err := formatter.Print(filings); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Exported %d filings to %s\n", len(filings), filingsExportFile)
+	return nil
+}
+
+// openLedger opens the filing ledger of the active profile
+func openLedger() (*internal.Ledger, error) {
+	if err := checkProfile(); err != nil {
+		return nil, err
+	}
+	path, err := dataPath("filings", profileName()+".jsonl")
+	if err != nil {
+		return nil, err
+	}
+	return internal.OpenLedger(path)
+}
+
+// findFiling looks a filing up by ledger key or acknowledgment reference
+func findFiling(ledger *internal.Ledger, id string) (internal.Filing, bool) {
+	id = strings.TrimSpace(id)
+	if filing, ok := ledger.Get(strings.ToUpper(id)); ok {
+		return filing, true
+	}
+	for _, filing := range ledger.List() {
+		if filing.Reference != "" && strings.EqualFold(filing.Reference, id) {
+			return filing, true
+		}
+	}
+	return internal.Filing{}, false
+}
+
+// nilReturnFiling builds the ledger entry for a filed return
+func nilReturnFiling(request *kra.NILReturnRequest, result *kra.NILReturnResult) internal.Filing {
+	now := time.Now().UTC()
+	filing := internal.Filing{
+		Key:            internal.FilingKey(request.PINNumber, request.ObligationCode, request.Year, request.Month),
+		PIN:            internal.NormalizePIN(request.PINNumber),
+		ObligationCode: request.ObligationCode,
+		Period:         fmt.Sprintf("%04d%02d", request.Year, request.Month),
+		Status:         internal.FilingUnknown,
+		Reference:      internal.LookupField(result, "reference_number", "reference", "acknowledgement_number", "ack_number", "receipt_number"),
+		Message:        internal.LookupField(result, "message", "remarks"),
+		Profile:        profileName(),
+		FiledAt:        now,
+		UpdatedAt:      now,
+	}
+
+	switch {
+	case result.IsAccepted():
+		filing.Status = internal.FilingAccepted
+	case result.IsPending():
+		filing.Status = internal.FilingPending
+	case result.IsRejected():
+		filing.Status = internal.FilingRejected
+	}
+
+	if raw, err := json.Marshal(result); err == nil {
+		filing.Result = raw
+	}
+	return filing
+}
+
+// nilReturnSpec describes a NIL return submission; only a rejected return
+// counts as invalid
+func nilReturnSpec(client *kra.Client) batchSpec[*kra.NILReturnRequest, *kra.NILReturnResult] {
+	return batchSpec[*kra.NILReturnRequest, *kra.NILReturnResult]{
+		Command: "file-nil-return",
+		Key: func(r *kra.NILReturnRequest) string {
+			return internal.FilingKey(r.PINNumber, r.ObligationCode, r.Year, r.Month)
+		},
+		Call:  meteredCall("file-nil-return", client.FileNILReturn),
+		Valid: func(r *kra.NILReturnResult) bool { return !r.IsRejected() },
+	}
+}
+
+// duplicateFiling describes an earlier filing that blocks filing key again
+func duplicateFiling(ledger *internal.Ledger, key string) (string, bool) {
+	filing, ok := ledger.Get(key)
+	if !ok || !filing.Blocks() {
+		return "", false
+	}
+
+	reason := fmt.Sprintf("already filed on %s (%s", filing.FiledAt.Local().Format("2006-01-02"), filing.Status)
+	if filing.Reference != "" {
+		reason += ", reference " + filing.Reference
+	}
+	return reason + "); use --force to file again", true
+}
+
+// fileOnce files request with file unless the ledger, re-read under its file
+// lock, shows the return already filed by this or another kra-cli process,
+// in which case the error is a skipped rowError. The lock is held until the
+// filing is recorded. A return that was filed but could not be recorded is
+// returned along with the error.
+func fileOnce(ctx context.Context, ledger *internal.Ledger, file func(context.Context, *kra.NILReturnRequest) (*kra.NILReturnResult, error), request *kra.NILReturnRequest, force bool) (*kra.NILReturnResult, internal.Filing, error) {
+	unlock, err := ledger.Lock()
+	if err != nil {
+		return nil, internal.Filing{}, err
+	}
+	defer unlock()
+
+	key := internal.FilingKey(request.PINNumber, request.ObligationCode, request.Year, request.Month)
+	if reason, filed := duplicateFiling(ledger, key); filed && !force {
+		return nil, internal.Filing{}, skipInputf("%s", reason)
+	}
+
+	result, err := file(ctx, request)
+	if err != nil {
+		return nil, internal.Filing{}, err
+	}
+	filing := nilReturnFiling(request, result)
+	return result, filing, recordFiling(ledger, filing)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	kra "github.com/BerjisTech/kra-connect-go-sdk"
+)
+
+func TestFilingPINVariants(t *testing.T) {
+	ledger, err := internal.OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	defer ledger.Close()
+
+	request := &kra.NILReturnRequest{PINNumber: " p051234567a", ObligationCode: 4, Month: 1, Year: 2024}
+	filing := nilReturnFiling(request, &kra.NILReturnResult{})
+	if filing.PIN != "P051234567A" || filing.Key != "P051234567A/4/202401" {
+		t.Fatalf("expected the normalized PIN to be recorded, got %q and key %q", filing.PIN, filing.Key)
+	}
+	if err := ledger.Record(filing); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+
+	for _, pin := range []string{"P051234567A", "p051234567a", "P051234567A "} {
+		if _, ok := duplicateFiling(ledger, internal.FilingKey(pin, 4, 2024, 1)); !ok {
+			t.Errorf("expected %q to be refused as already filed", pin)
+		}
+	}
+	for _, id := range []string{"P051234567A/4/202401", "p051234567a/4/202401", " P051234567A/4/202401"} {
+		if _, ok := findFiling(ledger, id); !ok {
+			t.Errorf("findFiling(%q) found nothing", id)
+		}
+	}
+}
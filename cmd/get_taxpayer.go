@@ -1,11 +1,11 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 
 	"github.com/BerjisTech/kra-cli/internal"
+	kra "github.com/BerjisTech/kra-connect-go-sdk"
 	"github.com/spf13/cobra"
 )
 
@@ -16,14 +16,23 @@ var (
 var getTaxpayerCmd = &cobra.Command{
 	Use:   "get-taxpayer [PIN]",
 	Short: "Get taxpayer details",
-	Long:  `Retrieve comprehensive taxpayer details using the GavaConnect API.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runGetTaxpayer,
+	Long: `Retrieve comprehensive taxpayer details using the GavaConnect API.
+
+Examples:
+  kra-cli get-taxpayer P051234567A --show-obligations
+
+  # Report status, name and obligation changes as they happen
+  kra-cli get-taxpayer P051234567A --watch 1d`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGetTaxpayer,
 }
 
 func init() {
 	rootCmd.AddCommand(getTaxpayerCmd)
 	getTaxpayerCmd.Flags().BoolVar(&showObligations, "show-obligations", false, "Show tax obligations")
+	addCacheFlags(getTaxpayerCmd)
+	addWatchFlags(getTaxpayerCmd)
+	addNotifyFlag(getTaxpayerCmd)
 }
 
 func runGetTaxpayer(cmd *cobra.Command, args []string) error {
@@ -33,7 +42,9 @@ func runGetTaxpayer(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	ctx := context.Background()
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	formatter := internal.NewOutputFormatter(outputFmt)
 
 	pin := args[0]
@@ -42,7 +53,17 @@ func runGetTaxpayer(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Retrieving taxpayer details for PIN: %s\n", pin)
 	}
 
-	details, err := client.GetTaxpayerDetails(ctx, pin)
+	spec := taxpayerSpec(client)
+	spec.Call, err = cachedCall("get-taxpayer", internal.NormalizePIN, spec.Call)
+	if err != nil {
+		return err
+	}
+
+	if watching() {
+		return runWatch(ctx, "taxpayer "+pin, watchSingle(spec, pin))
+	}
+
+	details, err := historyCall(spec)(ctx, pin)
 	if err != nil {
 		return fmt.Errorf("failed to get taxpayer details: %w", err)
 	}
@@ -79,3 +100,14 @@ func runGetTaxpayer(cmd *cobra.Command, args []string) error {
 
 	return formatter.Print(details)
 }
+
+// taxpayerSpec describes a taxpayer details lookup. Any taxpayer found is a
+// valid answer, active or not.
+func taxpayerSpec(client *kra.Client) batchSpec[string, *kra.TaxpayerDetails] {
+	return batchSpec[string, *kra.TaxpayerDetails]{
+		Command: "get-taxpayer",
+		Key:     func(pin string) string { return pin },
+		Call:    meteredCall("get-taxpayer", client.GetTaxpayerDetails),
+		Valid:   func(*kra.TaxpayerDetails) bool { return true },
+	}
+}
@@ -0,0 +1,502 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyPIN        string
+	historyCommand    string
+	historySince      string
+	historyUntil      string
+	historyLimit      int
+	historyExportFile string
+)
+
+// Each command records its history through one writer, started with its first
+// recorded call and closed when the command ends
+var (
+	historyOnce   sync.Once
+	historyRun    string
+	historyWriter *historyBatcher
+)
+
+// historySecretFlags are flags whose values are redacted from the recorded
+// command line
+var historySecretFlags = []string{"--api-key", "--client-secret"}
+
+// historyExportRow is a history entry with its API response as a CSV column
+type historyExportRow struct {
+	ID         uint64    `json:"id"`
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Input      string    `json:"input"`
+	PIN        string    `json:"pin"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error"`
+	Profile    string    `json:"profile"`
+	Run        string    `json:"run"`
+	Invocation string    `json:"invocation"`
+	Result     string    `json:"result"`
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Search the history of past lookups and filings",
+	Long: `Search the local history of API calls made by kra-cli.
+
+When history is enabled, every PIN verification, TCC check, e-slip validation,
+taxpayer lookup and NIL return filing is recorded with its input, the answer
+GavaConnect gave and when, so past answers can be looked up later. History is
+kept per profile in ~/.kra-cli/history and is off until enabled:
+
+  kra-cli config set history true
+
+Examples:
+  # What did KRA say about a supplier since the start of the year?
+  kra-cli history search --pin P051234567A --since 2026-01-01
+
+  # Failed TCC checks in the last week
+  kra-cli history search --command check-tcc --since 7d
+
+  # Show one entry with the full API response
+  kra-cli history show 42
+
+  # Export matching entries
+  kra-cli history export --pin P051234567A --file supplier.csv
+  kra-cli history export --since 2026-03-01 --output json > march.json`,
+}
+
+var historySearchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "List recorded API calls, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runHistorySearch,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a recorded API call with its full response",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export recorded API calls as CSV or JSON",
+	Long: `Export recorded API calls as CSV (default) or JSON (--output json), with the
+full API response of each. Takes the same filters as history search.`,
+	Args: cobra.NoArgs,
+	RunE: runHistoryExport,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historySearchCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyExportCmd)
+
+	for _, cmd := range []*cobra.Command{historySearchCmd, historyExportCmd} {
+		cmd.Flags().StringVar(&historyPIN, "pin", "", "only calls about this PIN")
+		cmd.Flags().StringVar(&historyCommand, "command", "", "only calls made by this command (e.g. verify-pin, check-tcc)")
+		cmd.Flags().StringVar(&historySince, "since", "", "only calls on or after this date or time (e.g. 2026-01-01, 2026-01-01T08:00:00Z, 30d)")
+		cmd.Flags().StringVar(&historyUntil, "until", "", "only calls on or before this date or time")
+	}
+	historySearchCmd.Flags().IntVar(&historyLimit, "limit", 100, "show at most this many calls (0 for all)")
+	historyExportCmd.Flags().StringVar(&historyExportFile, "file", "", "write to this file instead of stdout")
+}
+
+func runHistorySearch(cmd *cobra.Command, args []string) error {
+	query, err := historyQuery()
+	if err != nil {
+		return err
+	}
+	query.Limit = historyLimit
+
+	history, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	entries, err := history.Search(query)
+	if err != nil {
+		return err
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(entries); err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "%d calls found in %s\n", len(entries), history.Path())
+	}
+	return nil
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return inputErrorf("invalid history id %q", args[0])
+	}
+
+	history, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	entry, ok, err := history.Get(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return inputErrorf("no history entry %d for profile %s", id, profileName())
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(entry); err != nil {
+		return err
+	}
+
+	// The table omits the raw response; show it below
+	if strings.EqualFold(outputFmt, "table") && len(entry.Result) > 0 {
+		var pretty interface{}
+		if json.Unmarshal(entry.Result, &pretty) == nil {
+			raw, _ := json.MarshalIndent(pretty, "", "  ")
+			fmt.Printf("\nResponse:\n%s\n", raw)
+		}
+	}
+	return nil
+}
+
+func runHistoryExport(cmd *cobra.Command, args []string) error {
+	query, err := historyQuery()
+	if err != nil {
+		return err
+	}
+
+	history, err := openHistory()
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	entries, err := history.Search(query)
+	if err != nil {
+		return err
+	}
+
+	format := outputFmt
+	if strings.EqualFold(format, "table") {
+		format = "csv"
+	}
+	formatter := internal.NewOutputFormatter(format)
+
+	// JSON keeps the response as structured data; CSV needs it as text
+	var data interface{} = entries
+	if strings.EqualFold(format, "csv") {
+		rows := make([]historyExportRow, len(entries))
+		for i, e := range entries {
+			rows[i] = historyExportRow{
+				ID: e.ID, Time: e.Time, Command: e.Command, Input: e.Input, PIN: e.PIN,
+				Status: e.Status, Error: e.Error, Profile: e.Profile, Run: e.Run,
+				Invocation: e.Invocation, Result: string(e.Result),
+			}
+		}
+		data = rows
+	}
+
+	if historyExportFile == "" {
+		return formatter.Print(data)
+	}
+
+	file, err := os.Create(historyExportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	formatter.Writer = file
+	if err := formatter.Print(data); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Exported %d calls to %s\n", len(entries), historyExportFile)
+	return nil
+}
+
+// historyQuery builds the search query from the filter flags
+func historyQuery() (internal.HistoryQuery, error) {
+	query := internal.HistoryQuery{
+		PIN:     strings.TrimSpace(historyPIN),
+		Command: historyCommand,
+	}
+
+	var err error
+	if historySince != "" {
+		if query.Since, err = parseHistoryTime(historySince, false); err != nil {
+			return query, inputErrorf("invalid --since: %w", err)
+		}
+	}
+	if historyUntil != "" {
+		if query.Until, err = parseHistoryTime(historyUntil, true); err != nil {
+			return query, inputErrorf("invalid --until: %w", err)
+		}
+	}
+	return query, nil
+}
+
+// parseHistoryTime parses a --since or --until value: an RFC 3339 time, a
+// date, or a duration before now such as 30d. A date used as an upper bound
+// includes the whole day.
+func parseHistoryTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if d, err := internal.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (YYYY-MM-DD), time (RFC 3339) or duration (e.g. 30d)", value)
+}
+
+// historyEnabled reports whether API calls are recorded in the history
+func historyEnabled() bool {
+	enabled, _ := strconv.ParseBool(settingString("history"))
+	return enabled
+}
+
+// openHistory opens the history database of the active profile for reading
+func openHistory() (*internal.History, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	return internal.OpenHistoryReadOnly(path)
+}
+
+// historyPath returns the history database location of the active profile
+func historyPath() (string, error) {
+	if err := checkProfile(); err != nil {
+		return "", err
+	}
+	return dataPath("history", profileName()+".db")
+}
+
+// historyCall wraps spec.Call so that every call is recorded in the history
+// with its input, status and result. Calls pass straight through when
+// history is off. A history that cannot be written never fails the call; the
+// entries left unrecorded are counted in a warning when the command ends.
+func historyCall[In, Out any](spec batchSpec[In, Out]) func(context.Context, In) (Out, error) {
+	if !historyEnabled() {
+		return spec.Call
+	}
+
+	return func(ctx context.Context, in In) (Out, error) {
+		out, err := spec.Call(ctx, in)
+
+		entry := internal.HistoryEntry{
+			Command: spec.Command,
+			Input:   spec.Key(in),
+			Status:  internal.StatusError,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Status = batchStatus(spec, out)
+			entry.Result, _ = json.Marshal(out)
+		}
+		entry.PIN = historyPINOf(entry.Input, out, err == nil)
+		recordHistory(entry)
+
+		return out, err
+	}
+}
+
+// historyPINOf finds the PIN a call was about: the first part of its input
+// key (e.g. "P051234567A/TCC123"), or else the PIN in its result
+func historyPINOf(key string, result interface{}, ok bool) string {
+	first, _, _ := strings.Cut(key, "/")
+	if internal.LintPIN(first).Valid {
+		return strings.ToUpper(first)
+	}
+	if ok {
+		if pin := internal.LookupField(result, "pin_number", "kra_pin", "pin"); internal.LintPIN(pin).Valid {
+			return strings.ToUpper(pin)
+		}
+	}
+	return ""
+}
+
+// recordHistory queues an entry for the history of the active profile
+func recordHistory(entry internal.HistoryEntry) {
+	historyOnce.Do(func() {
+		historyRun = newHistoryRun()
+		historyWriter = newHistoryBatcher()
+	})
+
+	entry.Profile = profileName()
+	entry.Run = historyRun
+	entry.Invocation = invocationLine(os.Args)
+	historyWriter.add(entry)
+}
+
+// closeHistory writes the entries still queued by the command and returns how
+// many of its entries could not be recorded, with the last error
+func closeHistory() (int, error) {
+	if historyWriter == nil {
+		return 0, nil
+	}
+	unrecorded, err := historyWriter.close()
+	historyOnce, historyWriter = sync.Once{}, nil
+	return unrecorded, err
+}
+
+// historyBatcher writes queued history entries in the background, all the
+// entries queued since the last write in one transaction. The database is
+// opened only for each write, so a long-running watch or gateway does not lock
+// other kra-cli processes out of it; entries that cannot be written, e.g.
+// while a history search holds the database, stay queued for the next write.
+type historyBatcher struct {
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	mu      sync.Mutex
+	pending []internal.HistoryEntry
+	err     error
+}
+
+func newHistoryBatcher() *historyBatcher {
+	w := &historyBatcher{
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// add queues entry for the next write
+func (w *historyBatcher) add(entry internal.HistoryEntry) {
+	w.mu.Lock()
+	w.pending = append(w.pending, entry)
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *historyBatcher) run() {
+	defer close(w.done)
+	for {
+		select {
+		case <-w.wake:
+			w.flush()
+		case <-w.stop:
+			w.flush()
+			return
+		}
+	}
+}
+
+// flush writes the queued entries, queueing them again if that fails
+func (w *historyBatcher) flush() {
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := writeHistory(batch); err != nil {
+		w.mu.Lock()
+		w.pending = append(batch, w.pending...)
+		w.err = err
+		w.mu.Unlock()
+	}
+}
+
+// close makes a last attempt to write the queued entries and returns how many
+// are left, with the last error
+func (w *historyBatcher) close() (int, error) {
+	close(w.stop)
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending), w.err
+}
+
+// writeHistory opens the history database, adds entries and closes it again
+func writeHistory(entries []internal.HistoryEntry) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+
+	store, err := internal.OpenHistory(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return store.RecordAll(entries)
+}
+
+// newHistoryRun returns a random identifier for the current invocation
+func newHistoryRun() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// invocationLine joins a command line, redacting the values of secret flags
+func invocationLine(args []string) string {
+	line := make([]string, 0, len(args))
+	redactNext := false
+	for i, arg := range args {
+		if i == 0 {
+			line = append(line, "kra-cli")
+			continue
+		}
+		if redactNext {
+			line = append(line, internal.Redacted)
+			redactNext = false
+			continue
+		}
+		for _, flag := range historySecretFlags {
+			if arg == flag {
+				redactNext = true
+			} else if strings.HasPrefix(arg, flag+"=") {
+				arg = flag + "=" + internal.Redacted
+			}
+		}
+		line = append(line, arg)
+	}
+	return strings.Join(line, " ")
+}
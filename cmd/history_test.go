@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestHistoryCall(t *testing.T) {
+	t.Setenv("KRA_DATA_DIR", t.TempDir())
+	t.Setenv("KRA_HISTORY", "true")
+	defer closeHistory()
+
+	type result struct {
+		Valid bool `json:"is_valid"`
+	}
+	spec := batchSpec[string, *result]{
+		Command: "check-tcc",
+		Key:     func(in string) string { return in },
+		Call: func(ctx context.Context, in string) (*result, error) {
+			if in == "P051234567A/TCC3" {
+				return nil, errors.New("timeout")
+			}
+			return &result{Valid: in == "P051234567A/TCC1"}, nil
+		},
+		Valid: func(r *result) bool { return r.Valid },
+	}
+
+	call := historyCall(spec)
+	for _, in := range []string{"P051234567A/TCC1", "P059876543B/TCC2", "P051234567A/TCC3"} {
+		call(context.Background(), in)
+	}
+
+	if unrecorded, err := closeHistory(); unrecorded != 0 {
+		t.Fatalf("%d entries not recorded: %v", unrecorded, err)
+	}
+
+	// Nothing keeps the database open once the command is done
+	historyStore, err := openHistory()
+	if err != nil {
+		t.Fatalf("openHistory returned error: %v", err)
+	}
+	defer historyStore.Close()
+
+	entries, err := historyStore.Search(internal.HistoryQuery{PIN: "P051234567A"})
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries for the PIN, got %+v", entries)
+	}
+	if e := entries[0]; e.Status != internal.StatusError || e.Error != "timeout" || e.Result != nil {
+		t.Errorf("unexpected failed entry: %+v", e)
+	}
+	if e := entries[1]; e.Status != internal.StatusOK || string(e.Result) != `{"is_valid":true}` || e.Profile != "default" || e.Run == "" {
+		t.Errorf("unexpected successful entry: %+v", e)
+	}
+
+	other, _ := historyStore.Search(internal.HistoryQuery{PIN: "P059876543B"})
+	if len(other) != 1 || other[0].Status != internal.StatusInvalid {
+		t.Errorf("expected one invalid entry for the other PIN, got %+v", other)
+	}
+
+	t.Setenv("KRA_HISTORY", "false")
+	historyCall(spec)(context.Background(), "P051234567A/TCC1")
+	if all, _ := historyStore.Search(internal.HistoryQuery{}); len(all) != 3 {
+		t.Errorf("expected no entry while history is off, got %d entries", len(all))
+	}
+}
+
+func TestHistoryWriterWaitsForReaders(t *testing.T) {
+	t.Setenv("KRA_DATA_DIR", t.TempDir())
+	defer closeHistory()
+
+	recordHistory(internal.HistoryEntry{Command: "verify-pin", PIN: "P051234567A"})
+	if unrecorded, err := closeHistory(); unrecorded != 0 {
+		t.Fatalf("%d entries not recorded: %v", unrecorded, err)
+	}
+
+	// A search holds the database while the command records its calls
+	path, _ := historyPath()
+	reader, err := internal.OpenHistoryReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryReadOnly returned error: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		recordHistory(internal.HistoryEntry{Command: "verify-pin", PIN: "P059876543B"})
+	}
+	time.Sleep(100 * time.Millisecond)
+	reader.Close()
+
+	if unrecorded, err := closeHistory(); unrecorded != 0 {
+		t.Fatalf("%d entries not recorded: %v", unrecorded, err)
+	}
+	store, err := openHistory()
+	if err != nil {
+		t.Fatalf("openHistory returned error: %v", err)
+	}
+	defer store.Close()
+	if all, _ := store.Search(internal.HistoryQuery{}); len(all) != 21 {
+		t.Errorf("expected all 21 entries once the search was done, got %d", len(all))
+	}
+}
+
+func TestHistoryWriterCountsUnrecorded(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(dir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KRA_DATA_DIR", dir)
+	defer closeHistory()
+
+	for i := 0; i < 3; i++ {
+		recordHistory(internal.HistoryEntry{Command: "verify-pin", PIN: "P051234567A"})
+	}
+	unrecorded, err := closeHistory()
+	if unrecorded != 3 || err == nil {
+		t.Errorf("expected 3 unrecorded entries with an error, got %d (%v)", unrecorded, err)
+	}
+}
+
+func TestInvocationLine(t *testing.T) {
+	args := []string{"/usr/local/bin/kra-cli", "verify-pin", "P051234567A", "--api-key", "k3y", "--client-secret=s3cret", "-o", "json"}
+	want := "kra-cli verify-pin P051234567A --api-key REDACTED --client-secret=REDACTED -o json"
+	if got := invocationLine(args); got != want {
+		t.Errorf("invocationLine = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintBatchFile string
+)
+
+// pinLintResult is one checked PIN, with its line number in batch mode
+type pinLintResult struct {
+	Line   int    `json:"line,omitempty"`
+	PIN    string `json:"pin"`
+	Valid  bool   `json:"valid"`
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// lintPinCmd represents the lint-pin command
+var lintPinCmd = &cobra.Command{
+	Use:   "lint-pin [PIN...]",
+	Short: "Check KRA PIN format offline",
+	Long: `Check the structure of KRA PINs without calling the API.
+
+A well-formed PIN is 11 characters: A (individual) or P (non-individual),
+nine digits and a trailing letter, e.g. P051234567A. Malformed PINs are
+reported with the reason. KRA does not publish a check-digit algorithm, so a
+well-formed PIN may still be unregistered; use verify-pin to confirm it.
+
+Examples:
+  # Check PINs given as arguments
+  kra-cli lint-pin P051234567A INVALID
+
+  # Check every PIN in a CSV file with a "pin" column
+  kra-cli lint-pin --batch pins.csv
+
+  # Skip malformed PINs when verifying a batch
+  kra-cli verify-pin --batch pins.csv --precheck`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if lintBatchFile == "" && len(args) == 0 {
+			return fmt.Errorf("requires either PIN arguments or --batch flag")
+		}
+		if lintBatchFile != "" && len(args) > 0 {
+			return fmt.Errorf("cannot use both PIN arguments and --batch flag")
+		}
+		return nil
+	},
+	RunE: runLintPin,
+}
+
+func init() {
+	rootCmd.AddCommand(lintPinCmd)
+	lintPinCmd.Flags().StringVar(&lintBatchFile, "batch", "", "CSV file containing PINs to check")
+}
+
+func runLintPin(cmd *cobra.Command, args []string) error {
+	formatter := internal.NewOutputFormatter(outputFmt)
+
+	var results []pinLintResult
+	if lintBatchFile != "" {
+		input, err := internal.ReadCSVInput(lintBatchFile)
+		if err != nil {
+			return withExitCode(exitInput, err)
+		}
+
+		pinCol := input.Column("pin")
+		if pinCol == -1 {
+			return inputErrorf("CSV file must have a 'pin' or 'PIN' column")
+		}
+
+		for _, row := range input.Rows {
+			if row.Err != nil {
+				results = append(results, pinLintResult{Line: row.Line, Reason: fmt.Sprintf("malformed CSV row: %v", row.Err)})
+				continue
+			}
+			results = append(results, lintResult(row.Line, row.Value(pinCol)))
+		}
+	} else {
+		for _, pin := range args {
+			results = append(results, lintResult(0, pin))
+		}
+	}
+
+	if err := formatter.Print(results); err != nil {
+		return err
+	}
+
+	malformed := 0
+	for _, r := range results {
+		if !r.Valid {
+			malformed++
+		}
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Checked %d PINs (%d malformed)\n", len(results), malformed)
+	}
+
+	if malformed > 0 && failOn == failOnInvalid {
+		return withExitCode(exitInvalid, fmt.Errorf("%d of %d PINs are malformed", malformed, len(results)))
+	}
+	return nil
+}
+
+// lintResult checks one PIN
+func lintResult(line int, pin string) pinLintResult {
+	check := internal.LintPIN(pin)
+	return pinLintResult{
+		Line:   line,
+		PIN:    check.PIN,
+		Valid:  check.Valid,
+		Type:   check.Type,
+		Reason: check.Reason,
+	}
+}
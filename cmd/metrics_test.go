@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestErrorClass(t *testing.T) {
+	commandStarted = true
+	defer func() { commandStarted = false }()
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{errors.New("boom"), errorClassAPI},
+		{fmt.Errorf("failed to verify PIN: %w", context.DeadlineExceeded), errorClassTimeout},
+		{fmt.Errorf("failed to verify PIN: %w", context.Canceled), errorClassCanceled},
+		{errors.New("request failed: 401 Unauthorized"), errorClassAuth},
+		{errors.New("PIN P051401234X not found"), errorClassAPI},
+		{inputErrorf("missing PIN"), errorClassInput},
+	}
+
+	for _, c := range cases {
+		if got := errorClass(c.err); got != c.want {
+			t.Errorf("errorClass(%v) = %q, expected %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestCheckReady(t *testing.T) {
+	server, served := mockGavaConnect(t)
+	useMockClient(t, server)
+	apiMetrics = internal.NewMetrics()
+	defer func() { apiMetrics = nil }()
+
+	client, err := createClient()
+	if err != nil {
+		t.Fatalf("createClient returned error: %v", err)
+	}
+	client.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := checkReady(context.Background()); err != nil {
+			t.Fatalf("checkReady returned error: %v", err)
+		}
+	}
+	if served("/v1/token/generate") != 1 {
+		t.Errorf("expected one token fetch through the relay, got %d", served("/v1/token/generate"))
+	}
+
+	refused := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer refused.Close()
+	tokenURL = refused.URL + "/v1/token/generate"
+	if client, err = createClient(); err != nil {
+		t.Fatalf("createClient returned error: %v", err)
+	}
+	client.Close()
+	if err := checkReady(context.Background()); err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Errorf("expected refused credentials to fail readiness, got %v", err)
+	}
+
+	mux := http.NewServeMux()
+	apiMetrics.Mount(mux, checkReady)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{`kra_token_refreshes_total{result="success"} 1`, `kra_token_refreshes_total{result="failure"} 1`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
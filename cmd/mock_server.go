@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockHost        string
+	mockPort        int
+	mockFixtures    string
+	mockInit        bool
+	mockLatency     time.Duration
+	mockJitter      time.Duration
+	mockErrorRate   float64
+	mockErrorStatus int
+)
+
+// mockServerCmd represents the mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a mock GavaConnect server for offline development",
+	Long: `Run a local server imitating the GavaConnect endpoints, driven by fixture
+files, so every command can run offline (e.g. in CI) by pointing --base-url
+and --token-url at it.
+
+Each .yaml, .yml or .json file in the fixtures directory holds a list of
+routes. A route matches on method, path and an optional substring of the
+request body or query string, and returns a canned JSON response:
+
+  routes:
+    - name: pin-valid
+      method: POST
+      path: /checker/v1/pinbypin
+      match: P051234567A     # optional
+      status: 200            # default 200
+      latency: 150ms         # fixed delay
+      jitter: 100ms          # plus a random delay up to this
+      error_rate: 0.1        # answer 10% of requests with error_status
+      error_status: 503      # default 503
+      body:
+        pin_number: P051234567A
+        is_valid: true
+
+A route with a "sequence" of responses answers its nth request with the nth
+response and then repeats the last one, to script retries and transient
+failures. Unmatched requests get 404 and are logged, which shows the paths
+your SDK version calls.
+
+Examples:
+  # Write sample fixtures and start the server
+  kra-cli mock-server --fixtures fixtures/ --init
+
+  # Run commands against it
+  kra-cli verify-pin P051234567A --base-url http://localhost:8080 \
+    --token-url "http://localhost:8080/v1/token/generate?grant_type=client_credentials" \
+    --client-id test --client-secret test
+
+  # Add latency and random failures to every route
+  kra-cli mock-server --fixtures fixtures/ --latency 500ms --error-rate 0.05`,
+	Args: cobra.NoArgs,
+	RunE: runMockServer,
+}
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+	mockServerCmd.Flags().StringVar(&mockHost, "host", "127.0.0.1", "address to listen on")
+	mockServerCmd.Flags().IntVar(&mockPort, "port", 8080, "port to listen on")
+	mockServerCmd.Flags().StringVar(&mockFixtures, "fixtures", "", "directory of fixture files (required)")
+	mockServerCmd.Flags().BoolVar(&mockInit, "init", false, "write sample fixtures into the fixtures directory first")
+	mockServerCmd.Flags().DurationVar(&mockLatency, "latency", 0, "delay added to every response without its own latency")
+	mockServerCmd.Flags().DurationVar(&mockJitter, "jitter", 0, "random extra delay up to this for every response")
+	mockServerCmd.Flags().Float64Var(&mockErrorRate, "error-rate", 0, "fraction of requests (0-1) answered with --error-status")
+	mockServerCmd.Flags().IntVar(&mockErrorStatus, "error-status", http.StatusServiceUnavailable, "HTTP status of injected failures")
+	mockServerCmd.MarkFlagRequired("fixtures")
+}
+
+func runMockServer(cmd *cobra.Command, args []string) error {
+	if mockErrorRate < 0 || mockErrorRate > 1 {
+		return inputErrorf("--error-rate must be between 0 and 1")
+	}
+
+	if mockInit {
+		written, err := internal.WriteMockFixtures(mockFixtures)
+		if err != nil {
+			return err
+		}
+		for _, name := range written {
+			fmt.Fprintf(os.Stderr, "✓ Wrote fixture: %s\n", name)
+		}
+	}
+
+	routes, err := internal.LoadMockFixtures(mockFixtures)
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+
+	server := &http.Server{
+		Addr: net.JoinHostPort(mockHost, strconv.Itoa(mockPort)),
+		Handler: internal.NewMockServer(routes, internal.MockOptions{
+			Latency:     mockLatency,
+			Jitter:      mockJitter,
+			ErrorRate:   mockErrorRate,
+			ErrorStatus: mockErrorStatus,
+			Log:         os.Stderr,
+		}),
+	}
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", server.Addr, err)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	fmt.Fprintf(os.Stderr, "Mock GavaConnect server listening on http://%s (%d routes, Ctrl-C to stop)\n", listener.Addr(), len(routes))
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("mock server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop mock server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var notifyFlag string
+
+// Notification target types
+const (
+	notifyWebhook = "webhook"
+	notifySlack   = "slack"
+	notifySMTP    = "smtp"
+)
+
+// notifyKey is the config section holding notification targets
+const notifyKey = "notify"
+
+// notifyTimeout bounds the delivery of one notification to one target
+const notifyTimeout = time.Minute
+
+// notifyFields are the settings of a notification target
+var notifyFields = map[string]bool{
+	"type":     true,
+	"url":      true,
+	"secret":   true,
+	"host":     true,
+	"port":     true,
+	"username": true,
+	"password": true,
+	"from":     true,
+	"to":       true,
+}
+
+// notifyNamePattern matches valid notification target names
+var notifyNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// notifier is a configured notification target
+type notifier struct {
+	name   string
+	kind   string
+	target internal.NotifyTarget
+}
+
+// notifyTarget describes a configured target for "notify list"
+type notifyTarget struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Destination string `json:"destination"`
+	Error       string `json:"error,omitempty"`
+}
+
+// notifiers are the targets selected with --notify, and notifyCommand the
+// command they report on
+var (
+	notifiers     []notifier
+	notifyCommand string
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Send monitoring results to webhooks, Slack or email",
+	Long: `Send monitoring results to webhooks, Slack or email.
+
+Notification targets are named and configured in the config file, per profile
+like other settings. Each has a type (webhook, slack or smtp) and its settings:
+
+  webhook  url, secret (optional, signs each request)
+  slack    url (a Slack-compatible incoming webhook)
+  smtp     host, port (default 587), username, password, from, to
+
+Batch and watch runs of verify-pin, check-tcc, validate-slip and get-taxpayer,
+and "tcc expiring", send a notification with --notify when something needs
+attention: invalid or failed records, changes since --compare-to or the
+previous watch round, or TCCs close to expiry. Runs with nothing to report send
+nothing, and a failed notification is reported as a warning without changing
+the exit code.
+
+Webhooks receive the notification as JSON. With a secret, the request carries
+X-KRA-CLI-Timestamp and X-KRA-CLI-Signature headers; the signature is
+"sha256=" followed by the hex HMAC-SHA256 of the timestamp, a dot and the body.
+
+Secrets and SMTP passwords are kept in the secret backend like api-key.
+
+Examples:
+  # Configure targets
+  kra-cli config set notify.ops.type slack
+  kra-cli config set notify.ops.url https://hooks.slack.com/services/T000/B000/XXXX
+  kra-cli config set notify.audit.type webhook
+  kra-cli config set notify.audit.url https://example.com/kra-hook
+  kra-cli config set notify.audit.secret s3cr3t
+  kra-cli config set notify.finance.type smtp
+  kra-cli config set notify.finance.host smtp.example.com
+  kra-cli config set notify.finance.username alerts@example.com
+  kra-cli config set notify.finance.password PASSWORD
+  kra-cli config set notify.finance.from alerts@example.com
+  kra-cli config set notify.finance.to finance@example.com,ops@example.com
+
+  # Check the configuration
+  kra-cli notify list
+  kra-cli notify test
+
+  # Notify every target, or only some
+  kra-cli verify-pin --batch suppliers.csv --notify
+  kra-cli check-tcc --batch tccs.csv --watch 6h --notify=ops,finance
+  kra-cli tcc expiring --within 14d --notify=finance`,
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test [target...]",
+	Short: "Send a test notification",
+	Long: `Send a test notification to the named targets, or to every configured
+target. Exits with an error if any of them cannot be reached.`,
+	RunE: runNotifyTest,
+}
+
+var notifyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List notification targets",
+	Args:  cobra.NoArgs,
+	RunE:  runNotifyList,
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+	notifyCmd.AddCommand(notifyListCmd)
+}
+
+// addNotifyFlag registers --notify on a command that reports monitoring
+// results. It must be added after the command's other PreRunE hooks.
+func addNotifyFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&notifyFlag, "notify", "", "notify configured targets of results needing attention: all, or a comma-separated list (see: kra-cli notify)")
+	cmd.Flags().Lookup("notify").NoOptDefVal = "all"
+
+	previous := cmd.PreRunE
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if previous != nil {
+			if err := previous(cmd, args); err != nil {
+				return err
+			}
+		}
+		if notifyFlag == "" {
+			return nil
+		}
+		// A target kept as a command default only applies to monitoring runs
+		if !notifyApplies(cmd) {
+			if cmd.Flags().Changed("notify") {
+				return inputErrorf("--notify only applies to --batch and --watch runs")
+			}
+			return nil
+		}
+
+		var err error
+		notifiers, err = selectNotifiers(notifyFlag)
+		notifyCommand = strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+		return err
+	}
+}
+
+// notifyApplies reports whether cmd is running in a mode that notifies:
+// with --batch or --watch on commands that have them, always otherwise
+func notifyApplies(cmd *cobra.Command) bool {
+	batch := cmd.Flags().Lookup("batch")
+	if batch != nil && batch.Value.String() != "" {
+		return true
+	}
+	if cmd.Flags().Lookup("watch") != nil && watching() {
+		return true
+	}
+	return batch == nil && cmd.Flags().Lookup("watch") == nil
+}
+
+// notifyTargetNames returns the names of the targets configured at the top
+// level of the config file or in the active profile
+func notifyTargetNames() []string {
+	seen := map[string]bool{}
+	for name := range viper.GetStringMap(notifyKey) {
+		seen[name] = true
+	}
+	if activeProfile != "" {
+		for name := range viper.GetStringMap(profilePath(activeProfile, notifyKey)) {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectNotifiers loads the targets named by a --notify value
+func selectNotifiers(value string) ([]notifier, error) {
+	var names []string
+	if value == "all" {
+		names = notifyTargetNames()
+		if len(names) == 0 {
+			return nil, inputErrorf("no notification targets configured (see: kra-cli notify --help)")
+		}
+	} else {
+		seen := map[string]bool{}
+		for _, name := range strings.Split(value, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	selected := make([]notifier, 0, len(names))
+	for _, name := range names {
+		n, err := loadNotifier(name)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, n)
+	}
+	return selected, nil
+}
+
+// loadNotifier builds the target configured under notify.<name>
+func loadNotifier(name string) (notifier, error) {
+	get := func(field string) string {
+		return strings.TrimSpace(settingString(notifyKey + "." + name + "." + field))
+	}
+	secret := func(field string) (string, error) {
+		key := notifyKey + "." + name + "." + field
+		return resolveSecret(key, settingString(key))
+	}
+	missing := func(field string) error {
+		return inputErrorf("notification target %s has no %s (set it with: kra-cli config set notify.%s.%s ...)", name, field, name, field)
+	}
+
+	n := notifier{name: name, kind: get("type")}
+	switch n.kind {
+	case notifyWebhook:
+		target := &internal.WebhookTarget{URL: get("url")}
+		if target.URL == "" {
+			return n, missing("url")
+		}
+		var err error
+		if target.Secret, err = secret("secret"); err != nil {
+			return n, err
+		}
+		n.target = target
+
+	case notifySlack:
+		target := &internal.SlackTarget{URL: get("url")}
+		if target.URL == "" {
+			return n, missing("url")
+		}
+		n.target = target
+
+	case notifySMTP:
+		target := &internal.SMTPTarget{Host: get("host"), Port: 587, Username: get("username"), From: get("from")}
+		if target.Host == "" {
+			return n, missing("host")
+		}
+		if port := get("port"); port != "" {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return n, inputErrorf("notification target %s has an invalid port: %s", name, port)
+			}
+			target.Port = p
+		}
+		if target.From == "" {
+			target.From = target.Username
+		}
+		if target.From == "" {
+			return n, missing("from")
+		}
+		for _, to := range strings.Split(get("to"), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				target.To = append(target.To, to)
+			}
+		}
+		if len(target.To) == 0 {
+			return n, missing("to")
+		}
+		var err error
+		if target.Password, err = secret("password"); err != nil {
+			return n, err
+		}
+		n.target = target
+
+	case "":
+		return n, inputErrorf("unknown notification target %q (configure it with: kra-cli config set notify.%s.type webhook|slack|smtp)", name, name)
+	default:
+		return n, inputErrorf("notification target %s has an invalid type %q (use webhook, slack or smtp)", name, n.kind)
+	}
+	return n, nil
+}
+
+// validateNotifySetting checks a notify.<name>.<field> config key and its value
+func validateNotifySetting(key, value string) error {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || !notifyNamePattern.MatchString(parts[1]) || !notifyFields[parts[2]] {
+		return inputErrorf("invalid notification setting: %s (expected notify.<name>.<field> with field type, url, secret, host, port, username, password, from or to)", key)
+	}
+
+	switch parts[2] {
+	case "type":
+		switch value {
+		case notifyWebhook, notifySlack, notifySMTP:
+		default:
+			return inputErrorf("invalid notification type: %s (use webhook, slack or smtp)", value)
+		}
+	case "url":
+		if !strings.HasPrefix(value, "https://") && !strings.HasPrefix(value, "http://") {
+			return inputErrorf("invalid notification url: %s (expected an http or https URL)", value)
+		}
+	case "port":
+		if p, err := strconv.Atoi(value); err != nil || p < 1 || p > 65535 {
+			return inputErrorf("invalid notification port: %s", value)
+		}
+	}
+	return nil
+}
+
+// isNotifySecretKey reports whether key is the secret or password of a
+// notification target
+func isNotifySecretKey(key string) bool {
+	parts := strings.Split(key, ".")
+	return len(parts) == 3 && parts[0] == notifyKey && (parts[2] == "secret" || parts[2] == "password")
+}
+
+// notifying reports whether this run sends notifications
+func notifying() bool {
+	return len(notifiers) > 0
+}
+
+// notify sends a notification to the --notify targets. Nothing is sent
+// without items, and failures are reported as warnings.
+func notify(ctx context.Context, event, summary string, items []internal.NotificationItem) {
+	if !notifying() || len(items) == 0 {
+		return
+	}
+
+	n := &internal.Notification{
+		Event:   event,
+		Command: notifyCommand,
+		Profile: profileName(),
+		Summary: summary,
+		Time:    time.Now().UTC().Truncate(time.Second),
+		Items:   items,
+	}
+	for _, target := range notifiers {
+		if err := sendNotification(ctx, target, n); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: notification to %s failed: %s\n", target.name, err)
+			continue
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Notified %s (%s)\n", target.name, target.target.Destination())
+		}
+	}
+}
+
+// sendNotification delivers n to one target
+func sendNotification(ctx context.Context, target notifier, n *internal.Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	return target.target.Notify(ctx, n)
+}
+
+// batchNotificationItems lists the invalid and failed records of a batch
+func batchNotificationItems[T any](records []internal.BatchRecord[T]) []internal.NotificationItem {
+	items := make([]internal.NotificationItem, 0)
+	for _, r := range records {
+		if r.Status == internal.StatusInvalid || r.Status == internal.StatusError {
+			items = append(items, internal.NotificationItem{Key: r.Input, Status: r.Status, Detail: r.Error})
+		}
+	}
+	return items
+}
+
+func runNotifyTest(cmd *cobra.Command, args []string) error {
+	if err := checkProfile(); err != nil {
+		return err
+	}
+
+	value := "all"
+	if len(args) > 0 {
+		value = strings.Join(args, ",")
+	}
+	targets, err := selectNotifiers(value)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	n := &internal.Notification{
+		Event:   internal.EventTest,
+		Command: "notify test",
+		Profile: profileName(),
+		Summary: "test notification",
+		Time:    time.Now().UTC().Truncate(time.Second),
+		Items:   []internal.NotificationItem{{Key: "P051234567A", Status: "test", Detail: "sample record"}},
+	}
+
+	failed := 0
+	for _, target := range targets {
+		if err := sendNotification(ctx, target, n); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s (%s): %s\n", target.name, target.target.Destination(), err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ Sent test notification to %s (%s)\n", target.name, target.target.Destination())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d notification targets failed", failed, len(targets))
+	}
+	return nil
+}
+
+func runNotifyList(cmd *cobra.Command, args []string) error {
+	if err := checkProfile(); err != nil {
+		return err
+	}
+
+	names := notifyTargetNames()
+	if len(names) == 0 {
+		fmt.Println("No notification targets configured (see: kra-cli notify --help)")
+		return nil
+	}
+
+	rows := make([]notifyTarget, 0, len(names))
+	for _, name := range names {
+		n, err := loadNotifier(name)
+		row := notifyTarget{Name: name, Type: n.kind}
+		if err != nil {
+			row.Error = err.Error()
+		} else {
+			row.Destination = n.target.Destination()
+		}
+		rows = append(rows, row)
+	}
+
+	return internal.NewOutputFormatter(outputFmt).Print(rows)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/viper"
+)
+
+func TestSelectNotifiers(t *testing.T) {
+	defer viper.Reset()
+	defer func() { activeProfile = "" }()
+
+	viper.Set("notify.ops.type", "slack")
+	viper.Set("notify.ops.url", "https://hooks.slack.com/services/T000/B000/XXXX")
+	viper.Set(profilePath("acme", "notify.finance.type"), "smtp")
+	viper.Set(profilePath("acme", "notify.finance.host"), "smtp.example.com")
+	viper.Set(profilePath("acme", "notify.finance.username"), "alerts@example.com")
+	viper.Set(profilePath("acme", "notify.finance.password"), "plain-password")
+	viper.Set(profilePath("acme", "notify.finance.to"), "finance@example.com, ops@example.com")
+	activeProfile = "acme"
+
+	targets, err := selectNotifiers("all")
+	if err != nil {
+		t.Fatalf("selectNotifiers returned error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].name != "finance" || targets[1].name != "ops" {
+		t.Fatalf("expected the finance and ops targets, got %+v", targets)
+	}
+
+	want := &internal.SMTPTarget{
+		Host:     "smtp.example.com",
+		Port:     587,
+		Username: "alerts@example.com",
+		Password: "plain-password",
+		From:     "alerts@example.com",
+		To:       []string{"finance@example.com", "ops@example.com"},
+	}
+	if got := targets[0].target; !reflect.DeepEqual(got, want) {
+		t.Errorf("finance target = %+v, expected %+v", got, want)
+	}
+
+	if _, err := selectNotifiers("ops,missing"); exitCode(err) != exitInput {
+		t.Errorf("expected an input error for an unknown target, got %v", err)
+	}
+}
+
+func TestValidateNotifySetting(t *testing.T) {
+	valid := map[string]string{
+		"notify.ops.type":     "webhook",
+		"notify.ops.url":      "https://example.com/hook",
+		"notify.mail-1.port":  "465",
+		"notify.mail-1.to":    "a@example.com,b@example.com",
+		"notify.ops.password": "secret",
+	}
+	for key, value := range valid {
+		if err := validateNotifySetting(key, value); err != nil {
+			t.Errorf("validateNotifySetting(%s, %s) returned error: %v", key, value, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"notify.ops":          "slack",
+		"notify.ops.type":     "teams",
+		"notify.ops.url":      "hooks.example.com",
+		"notify.ops.port":     "smtp",
+		"notify.ops.channel":  "#alerts",
+		"notify.Ops Team.url": "https://example.com",
+	}
+	for key, value := range invalid {
+		if err := validateNotifySetting(key, value); err == nil {
+			t.Errorf("validateNotifySetting(%s, %s) accepted an invalid setting", key, value)
+		}
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"api_key":               true,
+		"notify.ops.secret":     true,
+		"notify.mail.password":  true,
+		"notify.ops.url":        false,
+		"commands.x.secret":     false,
+		"notify.ops.secret.sub": false,
+	} {
+		if got := isSecretKey(key); got != want {
+			t.Errorf("isSecretKey(%s) = %v, expected %v", key, got, want)
+		}
+	}
+}
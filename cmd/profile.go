@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	profileFlag   string
+	activeProfile string
+)
+
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named configuration profiles",
+	Long: `Manage named configuration profiles.
+
+A profile holds its own credentials, URLs, timeout and default output format,
+so you can switch between sandbox and production or between clients without
+editing the config file. Settings not present in a profile fall back to the
+top-level values in the config file.
+
+The active profile is chosen by, in order of precedence:
+  1. the --profile flag
+  2. the KRA_PROFILE environment variable
+  3. the profile selected with "config profile use"
+
+Examples:
+  # Create a sandbox profile from the connection flags
+  kra-cli config profile create sandbox --base-url https://sbx.kra.go.ke \
+    --client-id KEY --client-secret SECRET
+
+  # Add settings to an existing profile
+  kra-cli --profile sandbox config set output json
+
+  # Make a profile the default
+  kra-cli config profile use sandbox
+
+  # Run a single command against another profile
+  kra-cli verify-pin P051234567A --profile acme-production
+
+  # List and delete profiles
+  kra-cli config profile list
+  kra-cli config profile delete sandbox`,
+}
+
+var configProfileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a profile",
+	Long: `Create a profile, storing any of --api-key, --client-id, --client-secret,
+--base-url, --token-url, --timeout and --output given on the command line.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigProfileCreate,
+}
+
+var configProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigProfileList,
+}
+
+var configProfileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the default profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigProfileUse,
+}
+
+var configProfileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigProfileDelete,
+}
+
+func init() {
+	configCmd.AddCommand(configProfileCmd)
+	configProfileCmd.AddCommand(configProfileCreateCmd)
+	configProfileCmd.AddCommand(configProfileListCmd)
+	configProfileCmd.AddCommand(configProfileUseCmd)
+	configProfileCmd.AddCommand(configProfileDeleteCmd)
+}
+
+func runConfigProfileCreate(cmd *cobra.Command, args []string) error {
+	name := strings.ToLower(args[0])
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+
+	file, path, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if file.IsSet(profilePath(name)) {
+		return inputErrorf("profile %q already exists", name)
+	}
+
+	// Store every connection flag given explicitly on the command line;
+	// secrets go to the secret backend
+	flags := cmd.Flags()
+	for flag, key := range map[string]string{
+		"api-key":       "api_key",
+		"client-id":     "client_id",
+		"client-secret": "client_secret",
+		"base-url":      "base_url",
+		"token-url":     "token_url",
+		"timeout":       "timeout",
+		"output":        "output",
+	} {
+		if flags.Changed(flag) {
+			if _, err := storeSecret(file, profilePath(name, key), name, key, flags.Lookup(flag).Value.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !file.IsSet(profilePath(name)) {
+		file.Set(profilePath(name), map[string]interface{}{})
+	}
+	if err := file.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Printf("✓ Profile created: %s\n", name)
+	fmt.Printf("  Config file: %s\n", path)
+	return nil
+}
+
+func runConfigProfileList(cmd *cobra.Command, args []string) error {
+	names := profileNames()
+	if len(names) == 0 {
+		fmt.Println("No profiles configured")
+		return nil
+	}
+
+	fmt.Println("Profiles:")
+	for _, name := range names {
+		marker := " "
+		if name == activeProfile {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+	return nil
+}
+
+func runConfigProfileUse(cmd *cobra.Command, args []string) error {
+	name := strings.ToLower(args[0])
+
+	file, path, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if !file.IsSet(profilePath(name)) {
+		return inputErrorf("profile %q not found", name)
+	}
+
+	file.Set("current_profile", name)
+	if err := file.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Printf("✓ Default profile: %s\n", name)
+	return nil
+}
+
+func runConfigProfileDelete(cmd *cobra.Command, args []string) error {
+	name := strings.ToLower(args[0])
+
+	file, path, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if !file.IsSet(profilePath(name)) {
+		return inputErrorf("profile %q not found", name)
+	}
+
+	values, _ := file.Get(profilePath(name)).(map[string]interface{})
+	flat := map[string]interface{}{}
+	flattenSettings(flat, "", values)
+	for key, value := range flat {
+		if isSecretKey(key) {
+			if err := deleteSecret(name, key, value); err != nil {
+				return err
+			}
+		}
+	}
+	if store, err := openSecretStore(secretBackend()); err == nil {
+		// Best effort: a cached OAuth token may or may not exist
+		_ = store.Delete(secretName(name, "oauth_token"))
+	}
+
+	settings := file.AllSettings()
+	deleteSetting(settings, profilePath(name))
+	if settings["current_profile"] == name {
+		delete(settings, "current_profile")
+	}
+
+	if err := writeConfigSettings(settings, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Profile deleted: %s\n", name)
+	return nil
+}
+
+// resolveProfile picks the active profile from --profile, KRA_PROFILE or the
+// config file. Profile names are case-insensitive, like all config keys.
+func resolveProfile() string {
+	if profileFlag != "" {
+		return strings.ToLower(profileFlag)
+	}
+	if env := os.Getenv("KRA_PROFILE"); env != "" {
+		return strings.ToLower(env)
+	}
+	return strings.ToLower(viper.GetString("current_profile"))
+}
+
+// checkProfile verifies that the active profile, if any, exists in the config file
+func checkProfile() error {
+	if activeProfile == "" || viper.IsSet(profilePath(activeProfile)) {
+		return nil
+	}
+	return inputErrorf("profile %q not found (see: kra-cli config profile list)", activeProfile)
+}
+
+// profileName returns the active profile name, or "default" when none is selected
+func profileName() string {
+	if activeProfile == "" {
+		return "default"
+	}
+	return activeProfile
+}
+
+// profilePath returns the config key of a profile, or of a setting within it
+func profilePath(name string, key ...string) string {
+	return strings.Join(append([]string{"profiles", name}, key...), ".")
+}
+
+// profileNames lists the configured profiles in alphabetical order
+func profileNames() []string {
+	names := make([]string, 0)
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateProfileName rejects names that cannot be used as config keys
+func validateProfileName(name string) error {
+	if name == "" || strings.ContainsAny(name, ". \t") {
+		return inputErrorf("invalid profile name %q (must not be empty or contain dots or spaces)", name)
+	}
+	return nil
+}
+
+// setting returns the effective value of a config key: the KRA_* environment
+// variable, then the active profile, then the top-level config or flag default
+func setting(key string) interface{} {
+	if value, ok := os.LookupEnv("KRA_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))); ok {
+		return value
+	}
+	if activeProfile != "" {
+		if value := viper.Get(profilePath(activeProfile, key)); value != nil {
+			return value
+		}
+	}
+	return viper.Get(key)
+}
+
+// settingString returns the effective value of key as a string
+func settingString(key string) string {
+	value := setting(key)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// settingInt returns the effective value of key as an integer, or 0 if unset or invalid
+func settingInt(key string) int {
+	n, _ := strconv.Atoi(settingString(key))
+	return n
+}
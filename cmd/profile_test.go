@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSettingPrefersProfile(t *testing.T) {
+	defer viper.Reset()
+	defer func() { activeProfile = "" }()
+
+	viper.Set("base_url", "https://top.example")
+	viper.Set("timeout", 30)
+	viper.Set(profilePath("sandbox", "base_url"), "https://sbx.kra.go.ke")
+
+	activeProfile = "sandbox"
+	if got := settingString("base_url"); got != "https://sbx.kra.go.ke" {
+		t.Fatalf("settingString(base_url) = %q, expected profile value", got)
+	}
+	if got := settingInt("timeout"); got != 30 {
+		t.Fatalf("settingInt(timeout) = %d, expected top-level fallback 30", got)
+	}
+
+	t.Setenv("KRA_BASE_URL", "https://env.example")
+	if got := settingString("base_url"); got != "https://env.example" {
+		t.Fatalf("settingString(base_url) = %q, expected environment value", got)
+	}
+}
+
+func TestDeleteSetting(t *testing.T) {
+	settings := map[string]interface{}{
+		"api_key": "key",
+		"profiles": map[string]interface{}{
+			"sandbox": map[string]interface{}{"output": "json", "timeout": 60},
+		},
+	}
+
+	deleteSetting(settings, profilePath("sandbox", "output"))
+	sandbox := settings["profiles"].(map[string]interface{})["sandbox"].(map[string]interface{})
+	if _, ok := sandbox["output"]; ok {
+		t.Fatalf("expected nested key to be deleted")
+	}
+	if _, ok := sandbox["timeout"]; !ok {
+		t.Fatalf("expected sibling key to remain")
+	}
+
+	deleteSetting(settings, "api_key")
+	if _, ok := settings["api_key"]; ok {
+		t.Fatalf("expected top-level key to be deleted")
+	}
+}
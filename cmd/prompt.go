@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdinReader is shared by prompts so buffered input is not lost between them
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// isInteractive reports whether stdin is a terminal
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// Without a terminal it fails, telling the user which flag skips the prompt.
+func confirm(question, skipFlag string) (bool, error) {
+	if !isInteractive() {
+		return false, inputErrorf("confirmation required: re-run with %s to proceed without a terminal", skipFlag)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil && answer == "" {
+		return false, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// choose asks the user to pick from a numbered list of n items shown on
+// stderr, and returns the 0-based indexes picked. An empty answer picks every
+// item. Without a terminal it fails like confirm.
+func choose(question string, n int, skipFlag string) ([]int, error) {
+	if !isInteractive() {
+		return nil, inputErrorf("selection required: re-run with %s to proceed without a terminal", skipFlag)
+	}
+
+	for {
+		fmt.Fprintf(os.Stderr, "%s (e.g. 1,3-4 or all) [all]: ", question)
+		answer, err := stdinReader.ReadString('\n')
+		if err != nil && answer == "" {
+			return nil, nil
+		}
+
+		picked, err := parseSelection(answer, n)
+		if err == nil {
+			return picked, nil
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+}
+
+// parseSelection parses a list of 1-based item numbers and ranges such as
+// "1,3-4" into sorted 0-based indexes
+func parseSelection(answer string, n int) ([]int, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" || answer == "all" {
+		picked := make([]int, n)
+		for i := range picked {
+			picked[i] = i
+		}
+		return picked, nil
+	}
+	if answer == "none" {
+		return nil, nil
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		lo, err1 := strconv.Atoi(first)
+		hi, err2 := strconv.Atoi(last)
+		if err1 != nil || err2 != nil || lo < 1 || hi > n || lo > hi {
+			return nil, fmt.Errorf("invalid selection %q: choose numbers between 1 and %d", part, n)
+		}
+		for i := lo; i <= hi; i++ {
+			seen[i-1] = true
+		}
+	}
+
+	picked := make([]int, 0, len(seen))
+	for i := range seen {
+		picked = append(picked, i)
+	}
+	sort.Ints(picked)
+	return picked, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   []int
+	}{
+		{"", []int{0, 1, 2, 3}},
+		{"all\n", []int{0, 1, 2, 3}},
+		{"none", nil},
+		{"2", []int{1}},
+		{"4, 1-2", []int{0, 1, 3}},
+		{"1 1 3-4", []int{0, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSelection(tt.answer, 4)
+		if err != nil {
+			t.Errorf("parseSelection(%q) returned error: %v", tt.answer, err)
+			continue
+		}
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSelection(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+	}
+
+	for _, answer := range []string{"0", "5", "3-2", "x", "1-"} {
+		if _, err := parseSelection(answer, 4); err == nil {
+			t.Errorf("parseSelection(%q) expected an error", answer)
+		}
+	}
+}
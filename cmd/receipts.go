@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	receiptDir       string
+	receiptPublicKey string
+
+	// receiptKey signs receipts; it is loaded before anything is filed
+	receiptKey ed25519.PrivateKey
+)
+
+var filingsVerifyReceiptCmd = &cobra.Command{
+	Use:   "verify-receipt <receipt.json>",
+	Short: "Verify the signature of a filing receipt",
+	Long: `Verify that a JSON receipt written with --receipt-dir is unchanged since it
+was signed, and that it was signed by the active profile's receipt key.
+
+A receipt carries the public key it was signed with, so a valid signature
+alone proves nothing: the key must also be the expected one. It is the
+active profile's receipt key unless --public-key gives another (base64, as
+in the receipt's signature.public_key). A receipt signed by any other key
+is reported as invalid.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFilingsVerifyReceipt,
+}
+
+func init() {
+	filingsVerifyReceiptCmd.Flags().StringVar(&receiptPublicKey, "public-key", "", "expected signing key (base64 Ed25519 public key) instead of the active profile's receipt key")
+	filingsCmd.AddCommand(filingsVerifyReceiptCmd)
+}
+
+func runFilingsVerifyReceipt(cmd *cobra.Command, args []string) error {
+	signed, err := internal.LoadSignedReceipt(args[0])
+	if err != nil {
+		return withExitCode(exitInput, err)
+	}
+
+	receipt, err := signed.Verify()
+	if err != nil {
+		return withExitCode(exitInvalid, fmt.Errorf("receipt %s is not valid: %w", args[0], err))
+	}
+	signer, err := checkReceiptSigner(signed)
+	if err != nil {
+		return withExitCode(exitInvalid, fmt.Errorf("receipt %s is not valid: %w", args[0], err))
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(receipt); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Signature valid\n")
+	fmt.Fprintf(os.Stderr, "  Signed by %s\n", signer)
+	return nil
+}
+
+// checkReceiptSigner checks that a receipt was signed by the expected key:
+// the one given with --public-key, or else the active profile's receipt key.
+// It returns a description of that key.
+func checkReceiptSigner(signed *internal.SignedReceipt) (string, error) {
+	expected, signer := receiptPublicKey, "the key given with --public-key"
+	if expected == "" {
+		key, err := loadReceiptKey(false)
+		if err != nil {
+			return "", fmt.Errorf("cannot check the signing key: %w (pass the expected key with --public-key)", err)
+		}
+		if key == nil {
+			return "", fmt.Errorf("profile %s has no receipt key to check the signing key %s against (pass the expected key with --public-key)", profileName(), signed.Signature.PublicKey)
+		}
+		expected = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		signer = fmt.Sprintf("the receipt key of profile %s", profileName())
+	}
+
+	if signed.Signature.PublicKey != expected {
+		return "", fmt.Errorf("signed by key %s, not %s", signed.Signature.PublicKey, signer)
+	}
+	return signer, nil
+}
+
+// addReceiptFlag registers --receipt-dir on a command that records filings
+func addReceiptFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&receiptDir, "receipt-dir", "", "write a signed JSON and a PDF acknowledgment receipt of each filing to this directory")
+}
+
+// prepareReceipts loads the receipt signing key when --receipt-dir is set, so
+// a missing secret backend is reported before anything is filed
+func prepareReceipts() error {
+	if receiptDir == "" || receiptKey != nil {
+		return nil
+	}
+
+	key, err := loadReceiptKey(true)
+	if err != nil {
+		return err
+	}
+	receiptKey = key
+	return nil
+}
+
+// loadReceiptKey returns the active profile's receipt signing key from the
+// secret store, creating one if create is set and none exists yet
+func loadReceiptKey(create bool) (ed25519.PrivateKey, error) {
+	backend := secretBackend()
+	if backend == secretBackendPlain {
+		return nil, inputErrorf("signed receipts need the keyring or file secret backend (see: kra-cli config set secret-backend)")
+	}
+
+	store, err := openSecretStore(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	name := secretName(profileName(), "receipt_key")
+	value, err := store.Get(name)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid receipt key in %s", store.Location())
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !errors.Is(err, internal.ErrSecretNotFound) {
+		return nil, fmt.Errorf("failed to read receipt key: %w", err)
+	}
+	if !create {
+		return nil, nil
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receipt key: %w", err)
+	}
+	if err := store.Set(name, base64.StdEncoding.EncodeToString(key.Seed())); err != nil {
+		return nil, fmt.Errorf("failed to store receipt key: %w", err)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Created receipt signing key for profile %s in %s\n", profileName(), store.Location())
+	}
+	return key, nil
+}
+
+// recordFiling records a filing in the ledger and, with --receipt-dir,
+// writes its receipts
+func recordFiling(ledger *internal.Ledger, filing internal.Filing) error {
+	if err := ledger.Record(filing); err != nil {
+		return err
+	}
+	if receiptDir == "" {
+		return nil
+	}
+
+	paths, err := internal.WriteReceipts(receiptDir, internal.ReceiptFromFiling(filing), receiptKey)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		for _, path := range paths {
+			fmt.Fprintf(os.Stderr, "  Receipt: %s\n", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+// memorySecrets is a secret store kept in memory
+type memorySecrets map[string]string
+
+func (m memorySecrets) Name() string     { return secretBackendFile }
+func (m memorySecrets) Location() string { return "memory" }
+func (m memorySecrets) Get(key string) (string, error) {
+	if value, ok := m[key]; ok {
+		return value, nil
+	}
+	return "", internal.ErrSecretNotFound
+}
+func (m memorySecrets) Set(key, value string) error { m[key] = value; return nil }
+func (m memorySecrets) Delete(key string) error     { delete(m, key); return nil }
+
+func TestCheckReceiptSigner(t *testing.T) {
+	t.Setenv("KRA_SECRET_BACKEND", secretBackendFile)
+	store := memorySecrets{}
+	secretStores[secretBackendFile] = store
+	defer delete(secretStores, secretBackendFile)
+
+	sign := func(key ed25519.PrivateKey) *internal.SignedReceipt {
+		signed, err := internal.SignReceipt(internal.Receipt{PIN: "P051234567A", ObligationCode: 4, Period: "202401"}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	_, forger, _ := ed25519.GenerateKey(rand.Reader)
+	forged := sign(forger)
+
+	if _, err := checkReceiptSigner(forged); err == nil {
+		t.Error("receipt accepted without a profile receipt key")
+	}
+
+	key, err := loadReceiptKey(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkReceiptSigner(sign(key)); err != nil {
+		t.Errorf("receipt signed by the profile key rejected: %v", err)
+	}
+	if _, err := checkReceiptSigner(forged); err == nil {
+		t.Error("receipt signed by another key accepted")
+	}
+
+	receiptPublicKey = base64.StdEncoding.EncodeToString(forger.Public().(ed25519.PublicKey))
+	defer func() { receiptPublicKey = "" }()
+	if _, err := checkReceiptSigner(forged); err != nil {
+		t.Errorf("receipt signed by the --public-key key rejected: %v", err)
+	}
+	if _, err := checkReceiptSigner(sign(key)); err == nil {
+		t.Error("receipt not signed by the --public-key key accepted")
+	}
+}
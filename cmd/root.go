@@ -2,10 +2,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/BerjisTech/kra-cli/internal"
 	kra "github.com/BerjisTech/kra-connect-go-sdk"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -23,6 +28,11 @@ var (
 	verbose      bool
 )
 
+// clientLimiter, when set before createClient, limits the rate of every
+// request the client sends to GavaConnect, token requests included; serve
+// uses it to share --rate between all callers
+var clientLimiter *internal.RateLimiter
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "kra-cli",
@@ -50,14 +60,43 @@ Configuration:
     kra-cli verify-pin P051234567A --api-key YOUR_API_KEY
 
   Or set the KRA_API_KEY environment variable:
-    export KRA_API_KEY=YOUR_API_KEY`,
+    export KRA_API_KEY=YOUR_API_KEY
+
+Exit Codes:
+  0  success; every record valid / return accepted
+  1  unclassified failure
+  2  at least one record invalid
+  3  authentication failure
+  4  network error or timeout
+  5  invalid arguments or input file
+  6  NIL return rejected
+
+  Use --fail-on to choose which outcomes produce a non-zero exit:
+    invalid (default)  invalid or failed records
+    error              failed records only
+    never              record outcomes never fail the command`,
+	// Execute prints errors itself so it can pick the exit code
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		// Errors from here on are runtime failures, not usage mistakes
+		cmd.SilenceUsage = true
+		if err := applyCommandDefaults(cmd); err != nil {
+			return err
+		}
+		return validateFailOn()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if unrecorded, histErr := closeHistory(); unrecorded > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d history entries not recorded: %s\n", unrecorded, histErr)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -66,6 +105,7 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kra-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "configuration profile to use (overrides KRA_PROFILE)")
 	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "KRA API key (overrides config)")
 	rootCmd.PersistentFlags().StringVar(&clientID, "client-id", "", "KRA OAuth client ID (Consumer Key)")
 	rootCmd.PersistentFlags().StringVar(&clientSecret, "client-secret", "", "KRA OAuth client secret (Consumer Secret)")
@@ -74,6 +114,9 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&timeout, "timeout", 30, "request timeout in seconds")
 	rootCmd.PersistentFlags().StringVarP(&outputFmt, "output", "o", "table", "output format: table, json, csv")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&failOn, "fail-on", failOnInvalid, "outcomes that produce a non-zero exit code: invalid, error, never")
+	rootCmd.PersistentFlags().StringVar(&recordFile, "record", "", "record API requests and responses to this cassette file (secrets redacted)")
+	rootCmd.PersistentFlags().StringVar(&replayFile, "replay", "", "answer API requests from this cassette file instead of the network")
 
 	// Bind flags to viper
 	viper.BindPFlag("api_key", rootCmd.PersistentFlags().Lookup("api-key"))
@@ -114,54 +157,86 @@ func initConfig() {
 		}
 	}
 
-	// Apply values from config/env to flags if flags weren't explicitly set
+	activeProfile = resolveProfile()
+	if verbose && activeProfile != "" {
+		fmt.Fprintf(os.Stderr, "Using profile: %s\n", activeProfile)
+	}
+
+	// Apply values from env/profile/config to flags if flags weren't explicitly set
 	if !rootCmd.PersistentFlags().Changed("api-key") {
-		apiKey = viper.GetString("api_key")
+		apiKey = settingString("api_key")
 	}
 	if !rootCmd.PersistentFlags().Changed("base-url") {
-		baseURL = viper.GetString("base_url")
+		baseURL = settingString("base_url")
 	}
 	if !rootCmd.PersistentFlags().Changed("token-url") {
-		tokenURL = viper.GetString("token_url")
+		tokenURL = settingString("token_url")
 	}
 	if !rootCmd.PersistentFlags().Changed("timeout") {
-		timeout = viper.GetInt("timeout")
+		timeout = settingInt("timeout")
 	}
 	if !rootCmd.PersistentFlags().Changed("client-id") {
-		clientID = viper.GetString("client_id")
+		clientID = settingString("client_id")
 	}
 	if !rootCmd.PersistentFlags().Changed("client-secret") {
-		clientSecret = viper.GetString("client_secret")
+		clientSecret = settingString("client_secret")
+	}
+	if !rootCmd.PersistentFlags().Changed("output") {
+		if output := settingString("output"); output != "" {
+			outputFmt = output
+		}
 	}
 }
 
 // getAPIKey retrieves the API key from flags, config, or environment
 func getAPIKey() (string, error) {
 	if apiKey != "" {
-		return apiKey, nil
+		return resolveSecret("api_key", apiKey)
 	}
 
-	key := viper.GetString("api_key")
+	key := settingString("api_key")
 	if key == "" {
-		return "", fmt.Errorf("authentication not configured. Provide --client-id/--client-secret or set an API key via --api-key / KRA_API_KEY")
+		return "", withExitCode(exitAuth, fmt.Errorf("authentication not configured. Provide --client-id/--client-secret or set an API key via --api-key / KRA_API_KEY"))
 	}
 
-	return key, nil
+	return resolveSecret("api_key", key)
 }
 
-// createClient creates a KRA client with the configured options
+// apiRelay and tokenRelay stand in for the API and token URLs in the SDK
+// client; see createClient
+var apiRelay, tokenRelay *internal.Relay
+
+// createClient creates a KRA client with the configured options.
+//
+// The SDK has no option for its HTTP client, so it is given the URLs of two
+// loopback relays instead of the API and token URLs. The relays forward every
+// request the SDK sends, the token exchange included, through the rate limit,
+// the token cache and --record/--replay, in that order from the network out.
 func createClient() (*kra.Client, error) {
+	if err := checkProfile(); err != nil {
+		return nil, err
+	}
+
 	opts := []kra.Option{
-		kra.WithBaseURL(baseURL),
 		kra.WithTimeout(time.Duration(timeout) * time.Second),
 	}
 
-	if tokenURL != "" {
-		opts = append(opts, kra.WithTokenURL(tokenURL))
+	var transport http.RoundTripper = http.DefaultTransport
+	if clientLimiter != nil {
+		transport = &internal.RateLimitTransport{Base: transport, Limiter: clientLimiter}
 	}
-
 	if clientID != "" && clientSecret != "" {
-		opts = append(opts, kra.WithClientCredentials(clientID, clientSecret))
+		secret, err := resolveSecret("client_secret", clientSecret)
+		if err != nil {
+			return nil, err
+		}
+		if replayFile == "" && tokenURL != "" {
+			transport = useTokenTransport(transport, secret)
+		}
+		opts = append(opts, kra.WithClientCredentials(clientID, secret))
+	} else if replayFile != "" && apiKey == "" && settingString("api_key") == "" {
+		// Replayed responses do not depend on the credentials
+		opts = append(opts, kra.WithAPIKey(internal.Redacted))
 	} else {
 		key, err := getAPIKey()
 		if err != nil {
@@ -174,5 +249,47 @@ func createClient() (*kra.Client, error) {
 		opts = append(opts, kra.WithDebug(true))
 	}
 
+	transport, err := withCassette(transport)
+	if err != nil {
+		return nil, err
+	}
+	if err := startRelays(transport); err != nil {
+		return nil, err
+	}
+	opts = append(opts, kra.WithBaseURL(apiRelay.URL()))
+	if tokenRelay != nil {
+		opts = append(opts, kra.WithTokenURL(tokenRelay.URL()))
+	}
+
 	return kra.NewClient(opts...)
 }
+
+// startRelays starts the relays to the API and token URLs through transport,
+// replacing those of an earlier client. Without a token URL the SDK's default
+// is used, unrelayed.
+func startRelays(transport http.RoundTripper) error {
+	for _, relay := range []*internal.Relay{apiRelay, tokenRelay} {
+		if relay != nil {
+			relay.Close()
+		}
+	}
+	apiRelay, tokenRelay = nil, nil
+
+	var err error
+	if apiRelay, err = internal.StartRelay(baseURL, transport); err != nil {
+		return inputErrorf("invalid --base-url: %w", err)
+	}
+	if tokenURL == "" {
+		return nil
+	}
+	if tokenRelay, err = internal.StartRelay(tokenURL, transport); err != nil {
+		return inputErrorf("invalid --token-url: %w", err)
+	}
+	return nil
+}
+
+// commandContext returns a context that is cancelled when the user presses
+// Ctrl-C or the process receives SIGTERM
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/viper"
+)
+
+// mockGavaConnect serves the mock fixtures and counts the requests per path
+func mockGavaConnect(t *testing.T) (*httptest.Server, func(path string) int) {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := internal.WriteMockFixtures(dir); err != nil {
+		t.Fatalf("WriteMockFixtures returned error: %v", err)
+	}
+	routes, err := internal.LoadMockFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadMockFixtures returned error: %v", err)
+	}
+
+	var mu sync.Mutex
+	served := map[string]int{}
+	mock := internal.NewMockServer(routes, internal.MockOptions{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		served[r.URL.Path]++
+		mu.Unlock()
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return served[path]
+	}
+}
+
+// useMockClient configures createClient for the mock server with client
+// credentials, and restores the configuration when the test ends
+func useMockClient(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	t.Cleanup(func() {
+		tokenTransport, recorder, recordFile = nil, nil, ""
+		baseURL, tokenURL, clientID, clientSecret = "", "", "", ""
+		viper.Reset()
+	})
+
+	viper.Set("secret_backend", secretBackendPlain)
+	baseURL, tokenURL = server.URL, server.URL+"/v1/token/generate?grant_type=client_credentials"
+	clientID, clientSecret = "mock-client", "mock-secret"
+}
+
+// sendAsSDK sends a request to a relay URL the way the SDK client would
+func sendAsSDK(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(clientID, clientSecret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+func TestClientRelays(t *testing.T) {
+	server, served := mockGavaConnect(t)
+	useMockClient(t, server)
+	recordFile = filepath.Join(t.TempDir(), "cassette.yaml")
+
+	original := http.DefaultTransport
+	client, err := createClient()
+	if err != nil {
+		t.Fatalf("createClient returned error: %v", err)
+	}
+	defer client.Close()
+
+	if http.DefaultTransport != original {
+		t.Error("createClient replaced http.DefaultTransport")
+	}
+	if !strings.HasPrefix(apiRelay.URL(), "http://127.0.0.1:") || !strings.HasSuffix(tokenRelay.URL(), "/v1/token/generate?grant_type=client_credentials") {
+		t.Fatalf("unexpected relay URLs %s and %s", apiRelay.URL(), tokenRelay.URL())
+	}
+
+	tokenFetches := 0
+	tokenTransport.OnFetch = func(error) { tokenFetches++ }
+
+	// The SDK only knows the relay URLs, so whatever it sends arrives there
+	for i := 0; i < 2; i++ {
+		if resp := sendAsSDK(t, http.MethodGet, tokenRelay.URL(), ""); resp.StatusCode != http.StatusOK {
+			t.Fatalf("token request returned status %d", resp.StatusCode)
+		}
+	}
+	if resp := sendAsSDK(t, http.MethodPost, apiRelay.URL()+"/checker/v1/pinbypin", `{"KRAPIN":"P051234567A"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PIN request returned status %d", resp.StatusCode)
+	}
+
+	if tokenFetches != 1 || served("/v1/token/generate") != 1 {
+		t.Errorf("expected one token fetch for two token requests, got %d (%d served)", tokenFetches, served("/v1/token/generate"))
+	}
+	if served("/checker/v1/pinbypin") != 1 {
+		t.Errorf("expected the PIN request to reach the API, got %d", served("/checker/v1/pinbypin"))
+	}
+
+	cassette, err := internal.LoadCassette(recordFile)
+	if err != nil {
+		t.Fatalf("LoadCassette returned error: %v", err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Errorf("expected the recorder to see all 3 requests, got %d", len(cassette.Interactions))
+	}
+	for _, interaction := range cassette.Interactions {
+		if !strings.HasPrefix(interaction.Request.URL, server.URL) {
+			t.Errorf("recorded %s instead of the API URL", interaction.Request.URL)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// Secret backends selectable with "config set secret-backend"
+const (
+	secretBackendKeyring = "keyring" // OS keyring / Secret Service
+	secretBackendFile    = "file"    // passphrase-encrypted file, for headless systems
+	secretBackendPlain   = "plain"   // plaintext in the config file (not recommended)
+)
+
+// secretRefPrefix marks a config value that points at a secret backend
+// instead of holding the secret itself, e.g. "secret:keyring"
+const secretRefPrefix = "secret:"
+
+// secretKeys are the config keys whose values are kept in the secret backend
+var secretKeys = map[string]bool{
+	"api_key":        true,
+	"client_secret":  true,
+	"serve.api_keys": true,
+}
+
+// isSecretKey reports whether the value of a config key is kept in the
+// secret backend: credentials and the secrets of notification targets
+func isSecretKey(key string) bool {
+	return secretKeys[key] || isNotifySecretKey(key)
+}
+
+// secretStores caches opened stores so the passphrase is asked for only once
+var secretStores = map[string]internal.SecretStore{}
+
+// secretBackend returns the configured backend for new secrets
+func secretBackend() string {
+	if backend := settingString("secret_backend"); backend != "" {
+		return backend
+	}
+	return secretBackendKeyring
+}
+
+// openSecretStore returns the store for a backend name
+func openSecretStore(backend string) (internal.SecretStore, error) {
+	if store, ok := secretStores[backend]; ok {
+		return store, nil
+	}
+
+	var store internal.SecretStore
+	switch backend {
+	case secretBackendKeyring:
+		store = internal.NewKeyringStore("kra-cli")
+	case secretBackendFile:
+		path, err := secretsFilePath()
+		if err != nil {
+			return nil, err
+		}
+		store = internal.NewEncryptedFileStore(path, readPassphrase)
+	default:
+		return nil, inputErrorf("unknown secret backend %q (use keyring, file or plain)", backend)
+	}
+
+	secretStores[backend] = store
+	return store, nil
+}
+
+// secretsFilePath returns the location of the encrypted secrets file
+func secretsFilePath() (string, error) {
+	if path := os.Getenv("KRA_SECRETS_FILE"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find home directory: %w", err)
+	}
+	return filepath.Join(home, ".kra-cli.secrets"), nil
+}
+
+// readPassphrase reads the secrets file passphrase from KRA_SECRETS_PASSPHRASE
+// or, on an interactive terminal, prompts for it
+func readPassphrase() ([]byte, error) {
+	if passphrase := os.Getenv("KRA_SECRETS_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, withExitCode(exitAuth, fmt.Errorf("secrets file passphrase required: set KRA_SECRETS_PASSPHRASE"))
+	}
+
+	fmt.Fprint(os.Stderr, "Secrets passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
+// secretName is the name a secret is stored under in a backend
+func secretName(profile, key string) string {
+	return profile + "/" + key
+}
+
+// secretRefBackend returns the backend a config value points at, if it is a reference
+func secretRefBackend(value string) (string, bool) {
+	if !strings.HasPrefix(value, secretRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, secretRefPrefix), true
+}
+
+// storeSecret stores a config value at target in file. Secret keys are written
+// to the configured backend and only a reference lands in the config file.
+// It returns a description of where the value was stored.
+func storeSecret(file *viper.Viper, target, profile, key, value string) (string, error) {
+	backend := secretBackend()
+	if !isSecretKey(key) || backend == secretBackendPlain {
+		file.Set(target, value)
+		return "config file", nil
+	}
+
+	store, err := openSecretStore(backend)
+	if err != nil {
+		return "", err
+	}
+	if err := store.Set(secretName(profile, key), value); err != nil {
+		if backend == secretBackendKeyring {
+			return "", fmt.Errorf("%w (on headless systems use: kra-cli config set secret-backend file)", err)
+		}
+		return "", err
+	}
+
+	file.Set(target, secretRefPrefix+backend)
+	return store.Location(), nil
+}
+
+// deleteSecret removes the backend copy of a secret referenced by value
+func deleteSecret(profile, key string, value interface{}) error {
+	backend, ok := secretRefBackend(fmt.Sprintf("%v", value))
+	if !ok {
+		return nil
+	}
+
+	store, err := openSecretStore(backend)
+	if err != nil {
+		return err
+	}
+	return store.Delete(secretName(profile, key))
+}
+
+// resolveSecret returns the real value of a setting that may be a secret reference
+func resolveSecret(key, value string) (string, error) {
+	backend, ok := secretRefBackend(value)
+	if !ok {
+		return value, nil
+	}
+
+	store, err := openSecretStore(backend)
+	if err != nil {
+		return "", err
+	}
+
+	// Secrets set at the top level of the config live under "default"
+	profile := profileName()
+	if activeProfile != "" && !viper.IsSet(profilePath(activeProfile, key)) {
+		profile = "default"
+	}
+
+	secret, err := store.Get(secretName(profile, key))
+	if errors.Is(err, internal.ErrSecretNotFound) {
+		return "", withExitCode(exitAuth, fmt.Errorf("%s for profile %s is missing from the %s; set it again with: kra-cli config set %s", key, profile, backend, strings.ReplaceAll(key, "_", "-")))
+	}
+	if err != nil {
+		return "", withExitCode(exitAuth, fmt.Errorf("failed to read %s: %w", key, err))
+	}
+	return secret, nil
+}
@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	kra "github.com/BerjisTech/kra-connect-go-sdk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen string
+	serveRate   string
+)
+
+// serveAPIKeysKey is the setting holding the API keys callers authenticate with
+const serveAPIKeysKey = "serve.api_keys"
+
+// serveMaxBody limits the size of request bodies
+const serveMaxBody = 1 << 20
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a local REST gateway to the GavaConnect API",
+	Long: `Run a local REST gateway so systems that cannot embed Go can use the
+GavaConnect API over HTTP. Every request goes through one shared client, so
+OAuth tokens are reused, results come from the local cache when a TTL is
+configured (see: kra-cli cache), calls are recorded in the history database
+when it is enabled, and all callers share the --rate limit.
+
+Endpoints:
+  GET  /pins/{pin}          verify a PIN, like verify-pin
+  GET  /tcc?pin=..&tcc=..   check a TCC, like check-tcc
+  POST /tcc                 the same, with {"pin": "...", "tcc": "..."}
+  GET  /eslips/{number}     validate an e-slip, like validate-slip
+  POST /nil-returns         file a NIL return, like file-nil-return, with
+                            {"pin": "...", "obligation_code": 4, "period": "202401"};
+                            returns already in the filing ledger get 409
+                            unless "force": true is given
+  GET  /taxpayers/{pin}     taxpayer details, like get-taxpayer
+
+Monitoring endpoints need no API key:
+  GET  /metrics             Prometheus metrics: requests, errors by class and
+                            latency per operation, cache hits, token
+                            refreshes and batch throughput
+  GET  /healthz             liveness; 200 while the gateway runs
+  GET  /readyz              readiness; 503 while no OAuth token can be obtained
+
+Responses are the API results as JSON. Errors have a JSON body:
+  {"error": {"code": "bad_request", "message": "..."}}
+with 400 for invalid requests, 401 without a valid API key, 404, 409, 502
+when GavaConnect fails or rejects the gateway's credentials, and 504 when it
+times out.
+
+Callers authenticate with one of the API keys in the serve.api-keys setting,
+a comma-separated list kept in the secret backend, sent as
+"Authorization: Bearer KEY" or "X-API-Key: KEY".
+
+Examples:
+  kra-cli config set serve.api-keys erp-4f9c2a,reports-81d07e
+  kra-cli serve --listen :8080
+
+  curl -H "Authorization: Bearer erp-4f9c2a" http://localhost:8080/pins/P051234567A
+  curl -H "X-API-Key: erp-4f9c2a" -d '{"pin": "P051234567A", "obligation_code": 4, "period": "202401"}' \
+    http://localhost:8080/nil-returns`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "address to listen on (e.g. :8080 for every interface)")
+	serveCmd.Flags().StringVar(&serveRate, "rate", "5/s", "maximum rate of GavaConnect requests across all callers (e.g. 5/s, 300/m, 0 for unlimited)")
+	addCacheFlags(serveCmd)
+	addReceiptFlag(serveCmd)
+}
+
+// tccQuery is the body of POST /tcc
+type tccQuery struct {
+	PIN string `json:"pin"`
+	TCC string `json:"tcc"`
+}
+
+// nilReturnBody is the body of POST /nil-returns
+type nilReturnBody struct {
+	PIN            string `json:"pin"`
+	ObligationCode int    `json:"obligation_code"`
+	Period         string `json:"period"`
+	Month          int    `json:"month"`
+	Year           int    `json:"year"`
+	Force          bool   `json:"force"`
+}
+
+// gateway serves the REST endpoints with one shared client
+type gateway struct {
+	verifyPIN     func(context.Context, string) (*kra.PINVerificationResult, error)
+	checkTCC      func(context.Context, *kra.TCCVerificationRequest) (*kra.TCCVerificationResult, error)
+	validateSlip  func(context.Context, string) (*kra.EslipValidationResult, error)
+	fileNILReturn func(context.Context, *kra.NILReturnRequest) (*kra.NILReturnResult, error)
+	getTaxpayer   func(context.Context, string) (*kra.TaxpayerDetails, error)
+
+	ledger *internal.Ledger
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	keys, err := serveAPIKeys()
+	if err != nil {
+		return err
+	}
+	if err := prepareReceipts(); err != nil {
+		return err
+	}
+
+	apiMetrics = internal.NewMetrics()
+	client, err := serveClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.Close()
+
+	g, err := newGateway(client, ledger)
+	if err != nil {
+		return err
+	}
+
+	// Probes and scrapes are neither authenticated nor logged
+	mux := http.NewServeMux()
+	apiMetrics.Mount(mux, checkReady)
+	mux.Handle("/", internal.LogRequests(os.Stderr, internal.RequireAPIKey(keys, g.routes())))
+
+	server := &http.Server{
+		Addr:              serveListen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", server.Addr, err)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	fmt.Fprintf(os.Stderr, "KRA gateway listening on http://%s (profile %s, Ctrl-C to stop)\n", listener.Addr(), profileName())
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("gateway failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, stop := context.WithTimeout(context.Background(), 30*time.Second)
+	defer stop()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop gateway: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "✓ Gateway stopped")
+	return nil
+}
+
+// serveClient creates the client shared by every gateway request, with its
+// requests to GavaConnect limited to --rate
+func serveClient() (*kra.Client, error) {
+	rate, err := internal.ParseRate(serveRate)
+	if err != nil {
+		return nil, inputErrorf("invalid --rate: %w", err)
+	}
+	clientLimiter = internal.NewRateLimiter(rate)
+	return createClient()
+}
+
+// serveAPIKeys returns the API keys callers may use
+func serveAPIKeys() ([]string, error) {
+	value, err := resolveSecret(serveAPIKeysKey, settingString(serveAPIKeysKey))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, withExitCode(exitAuth, fmt.Errorf("no API keys configured for callers; set them with: kra-cli config set serve.api-keys KEY[,KEY...]"))
+	}
+	return keys, nil
+}
+
+// newGateway builds the gateway calls from the same specs as the commands
+func newGateway(client *kra.Client, ledger *internal.Ledger) (*gateway, error) {
+	pinSpec, err := verifyPINSpec(client)
+	if err != nil {
+		return nil, err
+	}
+	taxpayer := taxpayerSpec(client)
+	taxpayer.Call, err = cachedCall("get-taxpayer", internal.NormalizePIN, taxpayer.Call)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gateway{
+		verifyPIN:     historyCall(pinSpec),
+		checkTCC:      historyCall(checkTCCSpec(client)),
+		validateSlip:  historyCall(validateSlipSpec(client)),
+		fileNILReturn: historyCall(nilReturnSpec(client)),
+		getTaxpayer:   historyCall(taxpayer),
+		ledger:        ledger,
+	}, nil
+}
+
+// routes returns the gateway's request router
+func (g *gateway) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pins/{pin}", g.handleVerifyPIN)
+	mux.HandleFunc("GET /tcc", g.handleCheckTCC)
+	mux.HandleFunc("POST /tcc", g.handleCheckTCC)
+	mux.HandleFunc("GET /eslips/{number}", g.handleValidateSlip)
+	mux.HandleFunc("POST /nil-returns", g.handleFileNILReturn)
+	mux.HandleFunc("GET /taxpayers/{pin}", g.handleGetTaxpayer)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		internal.WriteGatewayError(w, &internal.GatewayError{
+			Status:  http.StatusNotFound,
+			Code:    internal.GatewayNotFound,
+			Message: fmt.Sprintf("no endpoint for %s %s", r.Method, r.URL.Path),
+		})
+	})
+	return mux
+}
+
+func (g *gateway) handleVerifyPIN(w http.ResponseWriter, r *http.Request) {
+	result, err := g.verifyPIN(r.Context(), r.PathValue("pin"))
+	respond(w, result, err)
+}
+
+func (g *gateway) handleCheckTCC(w http.ResponseWriter, r *http.Request) {
+	query := tccQuery{PIN: r.URL.Query().Get("pin"), TCC: r.URL.Query().Get("tcc")}
+	if r.Method == http.MethodPost {
+		if err := decodeBody(w, r, &query); err != nil {
+			respond[any](w, nil, err)
+			return
+		}
+	}
+	if query.PIN == "" || query.TCC == "" {
+		respond[any](w, nil, badRequestf("pin and tcc are required"))
+		return
+	}
+
+	result, err := g.checkTCC(r.Context(), &kra.TCCVerificationRequest{KraPIN: query.PIN, TCCNumber: query.TCC})
+	respond(w, result, err)
+}
+
+func (g *gateway) handleValidateSlip(w http.ResponseWriter, r *http.Request) {
+	result, err := g.validateSlip(r.Context(), r.PathValue("number"))
+	respond(w, result, err)
+}
+
+func (g *gateway) handleGetTaxpayer(w http.ResponseWriter, r *http.Request) {
+	result, err := g.getTaxpayer(r.Context(), r.PathValue("pin"))
+	respond(w, result, err)
+}
+
+// handleFileNILReturn files a return and records it in the filing ledger,
+// refusing returns already filed unless the body sets force
+func (g *gateway) handleFileNILReturn(w http.ResponseWriter, r *http.Request) {
+	var body nilReturnBody
+	if err := decodeBody(w, r, &body); err != nil {
+		respond[any](w, nil, err)
+		return
+	}
+	if body.PIN == "" || body.ObligationCode == 0 {
+		respond[any](w, nil, badRequestf("pin and obligation_code are required"))
+		return
+	}
+	if body.Period == "" && (body.Month == 0 || body.Year == 0) {
+		respond[any](w, nil, badRequestf("period (YYYYMM), or month and year, are required"))
+		return
+	}
+	month, year, err := resolvePeriod(body.Period, body.Month, body.Year)
+	if err != nil {
+		respond[any](w, nil, err)
+		return
+	}
+
+	request := &kra.NILReturnRequest{
+		PINNumber:      body.PIN,
+		ObligationCode: body.ObligationCode,
+		Month:          month,
+		Year:           year,
+	}
+
+	// The ledger is re-read under its lock, so filings made meanwhile by the
+	// CLI or another gateway are seen
+	key := internal.FilingKey(request.PINNumber, request.ObligationCode, request.Year, request.Month)
+	result, _, err := fileOnce(r.Context(), g.ledger, g.fileNILReturn, request, body.Force)
+	var settled *rowError
+	if errors.As(err, &settled) {
+		filing, _ := g.ledger.Get(key)
+		respond[any](w, nil, &internal.GatewayError{
+			Status:  http.StatusConflict,
+			Code:    internal.GatewayConflict,
+			Message: fmt.Sprintf("%s already filed on %s (%s); set \"force\": true to file again", key, filing.FiledAt.Format("2006-01-02"), filing.Status),
+		})
+		return
+	}
+	if result != nil && err != nil {
+		// The return is filed either way; answering with an error would invite filing it again
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", key, err)
+		err = nil
+	}
+	respond(w, result, err)
+}
+
+// decodeBody decodes a JSON request body into v
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return badRequestf("invalid JSON body: %s", err)
+	}
+	return nil
+}
+
+func badRequestf(format string, args ...interface{}) *internal.GatewayError {
+	return &internal.GatewayError{Status: http.StatusBadRequest, Code: internal.GatewayBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// respond writes result as JSON, or err as a JSON error body
+func respond[T any](w http.ResponseWriter, result T, err error) {
+	if err != nil {
+		internal.WriteGatewayError(w, gatewayError(err))
+		return
+	}
+	internal.WriteJSON(w, http.StatusOK, result)
+}
+
+// gatewayError maps an error to its HTTP status and error code, classified
+// like the command exit codes
+func gatewayError(err error) *internal.GatewayError {
+	var gwErr *internal.GatewayError
+	if errors.As(err, &gwErr) {
+		return gwErr
+	}
+
+	switch exitCode(err) {
+	case exitInput:
+		return &internal.GatewayError{Status: http.StatusBadRequest, Code: internal.GatewayBadRequest, Message: err.Error()}
+	case exitAuth:
+		return &internal.GatewayError{Status: http.StatusBadGateway, Code: internal.GatewayUpstreamAuth, Message: err.Error()}
+	case exitNetwork:
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+			return &internal.GatewayError{Status: http.StatusGatewayTimeout, Code: internal.GatewayUpstreamTimeout, Message: err.Error()}
+		}
+	}
+	return &internal.GatewayError{Status: http.StatusBadGateway, Code: internal.GatewayUpstreamError, Message: err.Error()}
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	kra "github.com/BerjisTech/kra-connect-go-sdk"
+)
+
+func TestGatewayRoutes(t *testing.T) {
+	commandStarted = true
+	defer func() { commandStarted = false }()
+
+	ledger, err := internal.OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	defer ledger.Close()
+
+	filed := 0
+	g := &gateway{
+		verifyPIN: func(ctx context.Context, pin string) (*kra.PINVerificationResult, error) {
+			return &kra.PINVerificationResult{IsValid: pin == "P051234567A"}, nil
+		},
+		checkTCC: func(ctx context.Context, req *kra.TCCVerificationRequest) (*kra.TCCVerificationResult, error) {
+			return nil, fmt.Errorf("KRA API error: 401 unauthorized")
+		},
+		validateSlip: func(ctx context.Context, number string) (*kra.EslipValidationResult, error) {
+			return nil, fmt.Errorf("request failed: %w", context.DeadlineExceeded)
+		},
+		fileNILReturn: func(ctx context.Context, req *kra.NILReturnRequest) (*kra.NILReturnResult, error) {
+			filed++
+			return &kra.NILReturnResult{}, nil
+		},
+		getTaxpayer: func(ctx context.Context, pin string) (*kra.TaxpayerDetails, error) {
+			return nil, errors.New("unexpected call")
+		},
+		ledger: ledger,
+	}
+	server := httptest.NewServer(g.routes())
+	defer server.Close()
+
+	nilReturn := `{"pin": "P051234567A", "obligation_code": 4, "period": "202401"}`
+	cases := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/pins/P051234567A", "", http.StatusOK, ""},
+		{"GET", "/tcc?pin=P051234567A&tcc=TCC123456", "", http.StatusBadGateway, internal.GatewayUpstreamAuth},
+		{"POST", "/tcc", `{"pin": "P051234567A"}`, http.StatusBadRequest, internal.GatewayBadRequest},
+		{"GET", "/eslips/ES123", "", http.StatusGatewayTimeout, internal.GatewayUpstreamTimeout},
+		{"POST", "/nil-returns", nilReturn, http.StatusOK, ""},
+		{"POST", "/nil-returns", nilReturn, http.StatusConflict, internal.GatewayConflict},
+		{"POST", "/nil-returns", `{"pin": "P051234567A", "obligation_code": 4, "period": "2024-01"}`, http.StatusBadRequest, internal.GatewayBadRequest},
+		{"POST", "/nil-returns", `{"pin": "P051234567A", "obligation": 4}`, http.StatusBadRequest, internal.GatewayBadRequest},
+		{"DELETE", "/pins/P051234567A", "", http.StatusNotFound, internal.GatewayNotFound},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.method, server.URL+c.path, strings.NewReader(c.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", c.method, c.path, err)
+		}
+
+		var body struct {
+			IsValid bool                  `json:"is_valid"`
+			Error   internal.GatewayError `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if resp.StatusCode != c.status || body.Error.Code != c.code {
+			t.Errorf("%s %s: got %d %q, expected %d %q", c.method, c.path, resp.StatusCode, body.Error.Code, c.status, c.code)
+		}
+		if c.path == "/pins/P051234567A" && c.method == "GET" && !body.IsValid {
+			t.Errorf("expected the verification result in the body")
+		}
+	}
+
+	if filed != 1 {
+		t.Errorf("expected one return filed, got %d", filed)
+	}
+	if _, ok := ledger.Get(internal.FilingKey("P051234567A", 4, 2024, 1)); !ok {
+		t.Errorf("expected the filing to be recorded in the ledger")
+	}
+
+	// A return filed from the CLI while the gateway runs is not filed again
+	cli, err := internal.OpenLedger(ledger.Path())
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	defer cli.Close()
+	request := &kra.NILReturnRequest{PINNumber: "P051234567A", ObligationCode: 4, Month: 2, Year: 2024}
+	if err := cli.Record(nilReturnFiling(request, &kra.NILReturnResult{})); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+
+	resp, err := http.Post(server.URL+"/nil-returns", "application/json", strings.NewReader(`{"pin": "p051234567a", "obligation_code": 4, "period": "202402"}`))
+	if err != nil {
+		t.Fatalf("POST /nil-returns: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict || filed != 1 {
+		t.Errorf("expected a return filed by another process to get 409, got %d with %d filed", resp.StatusCode, filed)
+	}
+}
+
+func TestServeClientSharesRateAndToken(t *testing.T) {
+	server, served := mockGavaConnect(t)
+	useMockClient(t, server)
+	defer func(rate string) { serveRate, clientLimiter = rate, nil }(serveRate)
+	serveRate = "20/s"
+
+	client, err := serveClient()
+	if err != nil {
+		t.Fatalf("serveClient returned error: %v", err)
+	}
+	defer client.Close()
+
+	send := func(ctx context.Context, method, url, body string) error {
+		req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(clientID, clientSecret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s %s returned HTTP %d", method, url, resp.StatusCode)
+		}
+		return nil
+	}
+	// Stands in for the SDK's VerifyPIN, which only knows the relay URLs:
+	// it asks the token relay for a token, then the API relay for the check
+	g := &gateway{
+		verifyPIN: func(ctx context.Context, pin string) (*kra.PINVerificationResult, error) {
+			if err := send(ctx, http.MethodGet, tokenRelay.URL(), ""); err != nil {
+				return nil, err
+			}
+			if err := send(ctx, http.MethodPost, apiRelay.URL()+"/checker/v1/pinbypin", `{"KRAPIN":"`+pin+`"}`); err != nil {
+				return nil, err
+			}
+			return &kra.PINVerificationResult{IsValid: true}, nil
+		},
+	}
+	gw := httptest.NewServer(g.routes())
+	defer gw.Close()
+
+	const callers = 5
+	statuses := make([]int, callers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http.Get(gw.URL + "/pins/P051234567A")
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("caller %d got status %d", i, status)
+		}
+	}
+	if served("/v1/token/generate") != 1 {
+		t.Errorf("expected the callers to share one token fetch, got %d", served("/v1/token/generate"))
+	}
+	if served("/checker/v1/pinbypin") != callers {
+		t.Errorf("expected %d PIN checks, got %d", callers, served("/checker/v1/pinbypin"))
+	}
+	// The token fetch and the checks leave at most 20 per second
+	if min := callers * 50 * time.Millisecond; elapsed < min {
+		t.Errorf("%d requests took %s, expected at least %s at --rate 20/s", callers+1, elapsed, min)
+	}
+}
@@ -0,0 +1,506 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	kra "github.com/BerjisTech/kra-connect-go-sdk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchPIN       string
+	watchLabel     string
+	watchBatchFile string
+	expiringWithin string
+	expiringAll    bool
+	icsFile        string
+	icsRemind      []string
+	icsRefresh     bool
+)
+
+// TCC statuses in expiry reports
+const (
+	tccValid     = "valid"
+	tccExpiring  = "expiring"
+	tccExpired   = "expired"
+	tccUnchecked = "unchecked"
+)
+
+// tccExpiry is the expiry status of one watched TCC
+type tccExpiry struct {
+	TCC       string `json:"tcc"`
+	PIN       string `json:"pin"`
+	Label     string `json:"label,omitempty"`
+	Status    string `json:"status"`
+	ExpiresOn string `json:"expires_on,omitempty"`
+	DaysLeft  int    `json:"days_left"`
+	CheckedAt string `json:"checked_at,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var tccCmd = &cobra.Command{
+	Use:   "tcc",
+	Short: "Monitor Tax Compliance Certificates for expiry",
+	Long: `Keep a watchlist of supplier TCCs, re-check them for upcoming expiry and
+export expiry reminders to a calendar.
+
+The watchlist is kept per profile. Each check records whether the TCC is
+valid and when it expires.
+
+Examples:
+  # Watch a supplier's TCC
+  kra-cli tcc watchlist add KRAWRN1234567890 --pin P051234567A --label "Acme Ltd"
+
+  # Import a spreadsheet export with tcc, pin and label columns
+  kra-cli tcc watchlist add --batch suppliers.csv
+
+  # Re-check every TCC and show those expiring within 30 days
+  kra-cli tcc expiring --within 30d
+
+  # Write expiry reminders for a shared calendar
+  kra-cli tcc export-ics --file tcc-expiry.ics`,
+}
+
+var tccWatchlistCmd = &cobra.Command{
+	Use:   "watchlist",
+	Short: "Manage the TCC watchlist",
+}
+
+var tccWatchlistAddCmd = &cobra.Command{
+	Use:   "add [TCC]",
+	Short: "Add a TCC to the watchlist",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if watchBatchFile != "" {
+			if len(args) > 0 || watchPIN != "" {
+				return fmt.Errorf("cannot use a TCC argument or --pin with --batch")
+			}
+			return nil
+		}
+		if len(args) != 1 || watchPIN == "" {
+			return fmt.Errorf("requires a TCC argument and --pin, or --batch")
+		}
+		return nil
+	},
+	RunE: runTccWatchlistAdd,
+}
+
+var tccWatchlistRemoveCmd = &cobra.Command{
+	Use:   "remove TCC...",
+	Short: "Remove TCCs from the watchlist",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runTccWatchlistRemove,
+}
+
+var tccWatchlistListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List watched TCCs with the result of their last check",
+	Args:  cobra.NoArgs,
+	RunE:  runTccWatchlistList,
+}
+
+var tccExpiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "Re-check watched TCCs and report those close to expiry",
+	Long: `Re-check every watched TCC with GavaConnect and report those that expire
+within --within, have expired, are no longer valid or could not be checked.
+
+Exits with code 2 when a TCC has expired or is invalid (see --fail-on).`,
+	Args: cobra.NoArgs,
+	RunE: runTccExpiring,
+}
+
+var tccExportICSCmd = &cobra.Command{
+	Use:   "export-ics",
+	Short: "Export TCC expiry dates as an iCalendar file",
+	Long: `Write an iCalendar (.ics) file with an all-day event on the expiry date of
+every watched TCC, with reminders before each. Expiry dates come from the last
+check (see: kra-cli tcc expiring); use --refresh to check again first.`,
+	Args: cobra.NoArgs,
+	RunE: runTccExportICS,
+}
+
+func init() {
+	rootCmd.AddCommand(tccCmd)
+	tccCmd.AddCommand(tccWatchlistCmd)
+	tccCmd.AddCommand(tccExpiringCmd)
+	tccCmd.AddCommand(tccExportICSCmd)
+	tccWatchlistCmd.AddCommand(tccWatchlistAddCmd)
+	tccWatchlistCmd.AddCommand(tccWatchlistRemoveCmd)
+	tccWatchlistCmd.AddCommand(tccWatchlistListCmd)
+
+	tccWatchlistAddCmd.Flags().StringVar(&watchPIN, "pin", "", "Taxpayer PIN the TCC was issued to")
+	tccWatchlistAddCmd.Flags().StringVar(&watchLabel, "label", "", "name to show for the TCC, e.g. the supplier")
+	tccWatchlistAddCmd.Flags().StringVar(&watchBatchFile, "batch", "", "CSV file of TCCs to add (columns: tcc, pin, optional label)")
+
+	tccWatchlistListCmd.Flags().StringVar(&expiringWithin, "within", "30d", "show TCCs expiring within this time as expiring")
+
+	tccExpiringCmd.Flags().StringVar(&expiringWithin, "within", "30d", "report TCCs expiring within this time (e.g. 30d, 2w)")
+	tccExpiringCmd.Flags().BoolVar(&expiringAll, "all", false, "report every watched TCC, not just those needing attention")
+	tccExpiringCmd.Flags().IntVar(&batchConcurrency, "concurrency", 4, "number of parallel requests")
+	tccExpiringCmd.Flags().StringVar(&batchRate, "rate", "5/s", "maximum request rate (e.g. 5/s, 300/m, 0 for unlimited)")
+	addNotifyFlag(tccExpiringCmd)
+
+	tccExportICSCmd.Flags().StringVar(&icsFile, "file", "", "write the calendar to this file instead of stdout")
+	tccExportICSCmd.Flags().StringSliceVar(&icsRemind, "remind", []string{"30d", "7d", "1d"}, "reminders before each expiry date")
+	tccExportICSCmd.Flags().BoolVar(&icsRefresh, "refresh", false, "re-check every TCC before exporting")
+	tccExportICSCmd.Flags().IntVar(&batchConcurrency, "concurrency", 4, "number of parallel requests with --refresh")
+	tccExportICSCmd.Flags().StringVar(&batchRate, "rate", "5/s", "maximum request rate with --refresh")
+}
+
+func runTccWatchlistAdd(cmd *cobra.Command, args []string) error {
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	var entries []internal.WatchEntry
+	if watchBatchFile != "" {
+		entries, err = readWatchEntries(watchBatchFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		entries = []internal.WatchEntry{{TCC: args[0], PIN: watchPIN, Label: watchLabel}}
+	}
+
+	var added, updated int
+	for _, entry := range entries {
+		if watchlist.Add(entry) {
+			updated++
+		} else {
+			added++
+		}
+	}
+
+	if err := watchlist.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Watching %d new TCCs (%d updated)\n", added, updated)
+	return nil
+}
+
+func runTccWatchlistRemove(cmd *cobra.Command, args []string) error {
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, tcc := range args {
+		if !watchlist.Remove(tcc) {
+			missing = append(missing, tcc)
+		}
+	}
+
+	if err := watchlist.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Removed %d TCCs from the watchlist\n", len(args)-len(missing))
+	if len(missing) > 0 {
+		return inputErrorf("not in the watchlist: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func runTccWatchlistList(cmd *cobra.Command, args []string) error {
+	within, err := internal.ParseDuration(expiringWithin)
+	if err != nil {
+		return inputErrorf("invalid --within: %w", err)
+	}
+
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]tccExpiry, len(watchlist.Entries))
+	for i := range watchlist.Entries {
+		rows[i] = expiryRow(&watchlist.Entries[i], time.Now(), within)
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	return formatter.Print(rows)
+}
+
+func runTccExpiring(cmd *cobra.Command, args []string) error {
+	within, err := internal.ParseDuration(expiringWithin)
+	if err != nil {
+		return inputErrorf("invalid --within: %w", err)
+	}
+
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+	if len(watchlist.Entries) == 0 {
+		return inputErrorf("the watchlist is empty (see: kra-cli tcc watchlist add)")
+	}
+
+	if err := refreshWatchlist(watchlist); err != nil {
+		return err
+	}
+
+	rows := make([]tccExpiry, 0, len(watchlist.Entries))
+	var expired, failed int
+	for i := range watchlist.Entries {
+		row := expiryRow(&watchlist.Entries[i], time.Now(), within)
+		switch row.Status {
+		case tccExpired, internal.StatusInvalid:
+			expired++
+		case internal.StatusError:
+			failed++
+		case tccValid, tccUnchecked:
+			if !expiringAll {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	if err := formatter.Print(rows); err != nil {
+		return err
+	}
+
+	items := expiryNotificationItems(rows)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "%d of %d watched TCCs need attention\n", len(items), len(watchlist.Entries))
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	notify(ctx, internal.EventTCCExpiring, fmt.Sprintf("%d of %d watched TCCs need attention", len(items), len(watchlist.Entries)), items)
+
+	if failed > 0 && failOn != failOnNever {
+		return withExitCode(exitFailure, fmt.Errorf("%d of %d TCCs could not be checked", failed, len(watchlist.Entries)))
+	}
+	if expired > 0 && failOn == failOnInvalid {
+		return withExitCode(exitInvalid, fmt.Errorf("%d of %d TCCs have expired or are invalid", expired, len(watchlist.Entries)))
+	}
+	return nil
+}
+
+func runTccExportICS(cmd *cobra.Command, args []string) error {
+	reminders := make([]time.Duration, 0, len(icsRemind))
+	for _, r := range icsRemind {
+		d, err := internal.ParseDuration(r)
+		if err != nil {
+			return inputErrorf("invalid --remind: %w", err)
+		}
+		reminders = append(reminders, d)
+	}
+
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	if icsRefresh {
+		if err := refreshWatchlist(watchlist); err != nil {
+			return err
+		}
+	}
+
+	events := make([]internal.CalendarEvent, 0, len(watchlist.Entries))
+	for _, entry := range watchlist.Entries {
+		if entry.ExpiresAt == nil {
+			continue
+		}
+		events = append(events, internal.CalendarEvent{
+			UID:         strings.ToLower(entry.TCC) + "@kra-cli",
+			Date:        *entry.ExpiresAt,
+			Summary:     fmt.Sprintf("TCC expires: %s (%s)", entry.Name(), entry.TCC),
+			Description: fmt.Sprintf("Tax Compliance Certificate %s for PIN %s expires today.", entry.TCC, entry.PIN),
+			Reminders:   reminders,
+		})
+	}
+
+	if skipped := len(watchlist.Entries) - len(events); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d TCCs with no known expiry date (see: kra-cli tcc expiring)\n", skipped)
+	}
+
+	out := os.Stdout
+	if icsFile != "" {
+		file, err := os.Create(icsFile)
+		if err != nil {
+			return fmt.Errorf("failed to create calendar file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := internal.WriteICS(out, "TCC expiry ("+profileName()+")", events, time.Now()); err != nil {
+		return fmt.Errorf("failed to write calendar: %w", err)
+	}
+
+	if icsFile != "" {
+		fmt.Fprintf(os.Stderr, "✓ Wrote %d expiry dates to %s\n", len(events), icsFile)
+	}
+	return nil
+}
+
+// expiryNotificationItems lists the TCCs needing attention for --notify
+func expiryNotificationItems(rows []tccExpiry) []internal.NotificationItem {
+	items := make([]internal.NotificationItem, 0, len(rows))
+	for _, row := range rows {
+		if row.Status == tccValid || row.Status == tccUnchecked {
+			continue
+		}
+		details := make([]string, 0, 2)
+		if row.Label != "" {
+			details = append(details, row.Label)
+		}
+		if row.Error != "" {
+			details = append(details, row.Error)
+		} else if row.ExpiresOn != "" {
+			details = append(details, "expires "+row.ExpiresOn)
+		}
+		items = append(items, internal.NotificationItem{Key: row.TCC, Status: row.Status, Detail: strings.Join(details, ", ")})
+	}
+	return items
+}
+
+// loadWatchlist loads the TCC watchlist of the active profile
+func loadWatchlist() (*internal.Watchlist, error) {
+	if err := checkProfile(); err != nil {
+		return nil, err
+	}
+	path, err := dataPath("watchlist", profileName()+".json")
+	if err != nil {
+		return nil, err
+	}
+	return internal.LoadWatchlist(path)
+}
+
+// readWatchEntries reads watchlist entries from a CSV file
+func readWatchEntries(path string) ([]internal.WatchEntry, error) {
+	input, err := internal.ReadCSVInput(path)
+	if err != nil {
+		return nil, withExitCode(exitInput, err)
+	}
+
+	tccCol := input.Column("tcc")
+	pinCol := input.Column("pin")
+	labelCol := input.Column("label", "name", "supplier")
+	if tccCol == -1 || pinCol == -1 {
+		return nil, inputErrorf("CSV file must have 'tcc' and 'pin' columns")
+	}
+
+	entries := make([]internal.WatchEntry, 0, len(input.Rows))
+	for _, row := range input.Rows {
+		if row.Err != nil {
+			return nil, inputErrorf("line %d: malformed CSV row: %w", row.Line, row.Err)
+		}
+		entry := internal.WatchEntry{TCC: row.Value(tccCol), PIN: row.Value(pinCol)}
+		if entry.TCC == "" || entry.PIN == "" {
+			return nil, inputErrorf("line %d: missing TCC or PIN", row.Line)
+		}
+		if labelCol != -1 {
+			entry.Label = row.Value(labelCol)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// refreshWatchlist re-checks every watched TCC and saves the results
+func refreshWatchlist(watchlist *internal.Watchlist) error {
+	opts, err := batchOptions()
+	if err != nil {
+		return err
+	}
+
+	client, err := createClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Checking %d TCCs...\n", len(watchlist.Entries))
+	}
+
+	indexes := make([]int, len(watchlist.Entries))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	verify := historyCall(checkTCCSpec(client))
+	results := internal.RunBatch(ctx, indexes, opts, func(ctx context.Context, i int) (*kra.TCCVerificationResult, error) {
+		entry := watchlist.Entries[i]
+		return verify(ctx, &kra.TCCVerificationRequest{KraPIN: entry.PIN, TCCNumber: entry.TCC})
+	})
+	if ctx.Err() != nil {
+		return fmt.Errorf("check interrupted: %w", ctx.Err())
+	}
+
+	now := time.Now().UTC()
+	for _, r := range results {
+		updateWatchEntry(&watchlist.Entries[r.Index], r.Value, r.Err, now)
+	}
+
+	return watchlist.Save()
+}
+
+// updateWatchEntry records the outcome of checking a watched TCC. A failed
+// check keeps the previous outcome so the expiry date is not lost.
+func updateWatchEntry(entry *internal.WatchEntry, result *kra.TCCVerificationResult, err error, now time.Time) {
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+
+	valid := result.IsValid
+	entry.CheckedAt = &now
+	entry.Valid = &valid
+	entry.Error = ""
+
+	if expiry := internal.LookupField(result, "expiry_date", "expires_at", "expiry", "valid_to", "valid_until"); expiry != "" {
+		expiresAt, err := internal.ParseExpiryDate(expiry)
+		if err != nil {
+			entry.Error = err.Error()
+			return
+		}
+		entry.ExpiresAt = &expiresAt
+	}
+}
+
+// expiryRow describes a watched TCC as of now, treating expiry within within
+// as expiring
+func expiryRow(entry *internal.WatchEntry, now time.Time, within time.Duration) tccExpiry {
+	row := tccExpiry{TCC: entry.TCC, PIN: entry.PIN, Label: entry.Label, Status: tccUnchecked, Error: entry.Error}
+	if entry.CheckedAt != nil {
+		row.CheckedAt = entry.CheckedAt.Local().Format("2006-01-02 15:04")
+	}
+
+	if entry.ExpiresAt != nil {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		row.ExpiresOn = entry.ExpiresAt.Format("2006-01-02")
+		row.DaysLeft = int(entry.ExpiresAt.Sub(today).Hours() / 24)
+	}
+
+	switch {
+	case entry.Error != "":
+		row.Status = internal.StatusError
+	case entry.Valid == nil:
+		row.Status = tccUnchecked
+	case entry.ExpiresAt != nil && row.DaysLeft < 0:
+		row.Status = tccExpired
+	case !*entry.Valid:
+		row.Status = internal.StatusInvalid
+	case entry.ExpiresAt != nil && time.Duration(row.DaysLeft)*24*time.Hour <= within:
+		row.Status = tccExpiring
+	default:
+		row.Status = tccValid
+	}
+	return row
+}
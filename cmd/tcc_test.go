@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestExpiryRow(t *testing.T) {
+	now := time.Date(2024, 6, 1, 15, 0, 0, 0, time.Local)
+	within := 30 * 24 * time.Hour
+	date := func(month time.Month, day int) *time.Time {
+		d := time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	valid, invalid := true, false
+
+	tests := []struct {
+		name     string
+		entry    internal.WatchEntry
+		status   string
+		daysLeft int
+	}{
+		{"never checked", internal.WatchEntry{}, tccUnchecked, 0},
+		{"valid", internal.WatchEntry{Valid: &valid, ExpiresAt: date(12, 31)}, tccValid, 213},
+		{"expiring", internal.WatchEntry{Valid: &valid, ExpiresAt: date(7, 1)}, tccExpiring, 30},
+		{"expires today", internal.WatchEntry{Valid: &valid, ExpiresAt: date(6, 1)}, tccExpiring, 0},
+		{"expired", internal.WatchEntry{Valid: &invalid, ExpiresAt: date(5, 31)}, tccExpired, -1},
+		{"invalid", internal.WatchEntry{Valid: &invalid}, internal.StatusInvalid, 0},
+		{"check failed", internal.WatchEntry{Valid: &valid, Error: "timeout"}, internal.StatusError, 0},
+	}
+
+	for _, tt := range tests {
+		row := expiryRow(&tt.entry, now, within)
+		if row.Status != tt.status || row.DaysLeft != tt.daysLeft {
+			t.Errorf("%s: expected %s with %d days left, got %+v", tt.name, tt.status, tt.daysLeft, row)
+		}
+	}
+}
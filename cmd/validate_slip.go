@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"os"
 
@@ -34,6 +33,9 @@ var validateSlipCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(validateSlipCmd)
 	validateSlipCmd.Flags().StringVar(&eslipBatchFile, "batch", "", "CSV file containing e-slips to validate")
+	addBatchFlags(validateSlipCmd)
+	addCompareFlag(validateSlipCmd)
+	addNotifyFlag(validateSlipCmd)
 }
 
 func runValidateSlip(cmd *cobra.Command, args []string) error {
@@ -43,7 +45,9 @@ func runValidateSlip(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	ctx := context.Background()
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	formatter := internal.NewOutputFormatter(outputFmt)
 
 	if eslipBatchFile != "" {
@@ -56,82 +60,60 @@ func runValidateSlip(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Validating e-slip: %s\n", eslip)
 	}
 
-	result, err := client.ValidateEslip(ctx, eslip)
+	result, err := historyCall(validateSlipSpec(client))(ctx, eslip)
 	if err != nil {
 		return fmt.Errorf("failed to validate e-slip: %w", err)
 	}
 
-	return formatter.Print(result)
-}
-
-func runValidateSlipBatch(ctx context.Context, client *kra.Client, formatter *internal.OutputFormatter) error {
-	file, err := os.Open(eslipBatchFile)
-	if err != nil {
-		return fmt.Errorf("failed to open batch file: %w", err)
+	if err := formatter.Print(result); err != nil {
+		return err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	return validityOutcome(result.IsValid, "e-slip "+eslip)
+}
 
-	header, err := reader.Read()
+func runValidateSlipBatch(ctx context.Context, client *kra.Client, formatter *internal.OutputFormatter) error {
+	input, err := internal.ReadCSVInput(eslipBatchFile)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
-	}
-
-	eslipCol := -1
-	for i, col := range header {
-		if col == "eslip" || col == "ESLIP" || col == "e-slip" || col == "E-SLIP" {
-			eslipCol = i
-			break
-		}
+		return withExitCode(exitInput, err)
 	}
 
+	eslipCol := input.Column("eslip", "e-slip")
 	if eslipCol == -1 {
-		return fmt.Errorf("CSV file must have an 'eslip' or 'e-slip' column")
+		return inputErrorf("CSV file must have an 'eslip' or 'e-slip' column")
 	}
 
-	eslips := make([]string, 0)
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			break
-		}
-
-		if eslipCol < len(record) {
-			eslips = append(eslips, record[eslipCol])
-		}
+	if len(input.Rows) == 0 {
+		return inputErrorf("no e-slips found in CSV file")
 	}
 
-	if len(eslips) == 0 {
-		return fmt.Errorf("no e-slips found in CSV file")
-	}
+	inputs := parseBatchRows(input, func(row internal.InputRow) (string, error) {
+		eslip := row.Value(eslipCol)
+		if eslip == "" {
+			return "", inputErrorf("missing e-slip number")
+		}
+		return eslip, nil
+	})
 
 	if verbose {
-		fmt.Fprintf(os.Stderr, "Validating %d e-slips...\n", len(eslips))
+		fmt.Fprintf(os.Stderr, "Validating %d e-slips...\n", len(inputs))
 	}
 
 	// Process each eslip individually (no batch method available)
-	results := make([]*kra.EslipValidationResult, 0, len(eslips))
-	for _, eslip := range eslips {
-		result, err := client.ValidateEslip(ctx, eslip)
-		if err != nil {
-			if verbose {
-				fmt.Fprintf(os.Stderr, "Warning: failed to validate e-slip %s: %v\n", eslip, err)
-			}
-			continue
-		}
-		results = append(results, result)
+	records, err := runBatch(ctx, validateSlipSpec(client), input, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to validate e-slips: %w", err)
 	}
 
-	if verbose {
-		validCount := 0
-		for _, r := range results {
-			if r.IsValid {
-				validCount++
-			}
-		}
-		fmt.Fprintf(os.Stderr, "Validated %d e-slips (%d valid, %d invalid)\n", len(results), validCount, len(results)-validCount)
-	}
+	return reportBatch(ctx, formatter, records)
+}
 
-	return formatter.Print(results)
+// validateSlipSpec describes an e-slip validation
+func validateSlipSpec(client *kra.Client) batchSpec[string, *kra.EslipValidationResult] {
+	return batchSpec[string, *kra.EslipValidationResult]{
+		Command: "validate-slip",
+		Key:     func(eslip string) string { return eslip },
+		Call:    meteredCall("validate-slip", client.ValidateEslip),
+		Valid:   func(r *kra.EslipValidationResult) bool { return r.IsValid },
+	}
 }
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"os"
 
@@ -13,6 +12,7 @@ import (
 
 var (
 	pinBatchFile string
+	pinPrecheck  bool
 )
 
 // verifyPinCmd represents the verify-pin command
@@ -33,6 +33,24 @@ Examples:
   # Verify multiple PINs from a CSV file
   kra-cli verify-pin --batch pins.csv
 
+  # Verify a large batch with 8 workers, at most 5 requests per second
+  kra-cli verify-pin --batch pins.csv --concurrency 8 --rate 5/s
+
+  # Record progress so an interrupted run can be resumed
+  kra-cli verify-pin --batch pins.csv --checkpoint run.journal
+  kra-cli verify-pin --batch pins.csv --checkpoint run.journal --resume
+
+  # Answer repeated lookups from the local cache for a day (see: kra-cli cache)
+  kra-cli config set cache.verify-pin-ttl 24h
+  kra-cli verify-pin --batch pins.csv
+
+  # Re-check every 6 hours and print only what changed
+  kra-cli verify-pin --batch pins.csv --watch 6h
+  kra-cli verify-pin --batch pins.csv --watch 6h --on-change ./notify.sh
+
+  # Skip API calls for malformed PINs (see: kra-cli lint-pin)
+  kra-cli verify-pin --batch pins.csv --precheck
+
   # The CSV file should have a header row with a "pin" column:
   # pin
   # P051234567A
@@ -57,6 +75,12 @@ Output Formats:
 func init() {
 	rootCmd.AddCommand(verifyPinCmd)
 	verifyPinCmd.Flags().StringVar(&pinBatchFile, "batch", "", "CSV file containing PINs to verify")
+	verifyPinCmd.Flags().BoolVar(&pinPrecheck, "precheck", false, "in batch mode, report malformed PINs as invalid without calling the API")
+	addBatchFlags(verifyPinCmd)
+	addCompareFlag(verifyPinCmd)
+	addWatchFlags(verifyPinCmd)
+	addCacheFlags(verifyPinCmd)
+	addNotifyFlag(verifyPinCmd)
 }
 
 func runVerifyPin(cmd *cobra.Command, args []string) error {
@@ -67,7 +91,8 @@ func runVerifyPin(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	ctx := context.Background()
+	ctx, cancel := commandContext()
+	defer cancel()
 
 	formatter := internal.NewOutputFormatter(outputFmt)
 
@@ -83,79 +108,93 @@ func runVerifyPin(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Verifying PIN: %s\n", pin)
 	}
 
-	result, err := client.VerifyPIN(ctx, pin)
+	spec, err := verifyPINSpec(client)
 	if err != nil {
-		return fmt.Errorf("failed to verify PIN: %w", err)
+		return err
 	}
 
-	return formatter.Print(result)
-}
+	if watching() {
+		return runWatch(ctx, "PIN "+pin, watchSingle(spec, pin))
+	}
 
-func runVerifyPinBatch(ctx context.Context, client *kra.Client, formatter *internal.OutputFormatter) error {
-	// Open CSV file
-	file, err := os.Open(pinBatchFile)
+	result, err := historyCall(spec)(ctx, pin)
 	if err != nil {
-		return fmt.Errorf("failed to open batch file: %w", err)
+		return fmt.Errorf("failed to verify PIN: %w", err)
+	}
+
+	if err := formatter.Print(result); err != nil {
+		return err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	return validityOutcome(result.IsValid, "PIN "+pin)
+}
 
-	// Read header
-	header, err := reader.Read()
+func runVerifyPinBatch(ctx context.Context, client *kra.Client, formatter *internal.OutputFormatter) error {
+	input, err := internal.ReadCSVInput(pinBatchFile)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		return withExitCode(exitInput, err)
 	}
 
 	// Find PIN column
-	pinCol := -1
-	for i, col := range header {
-		if col == "pin" || col == "PIN" {
-			pinCol = i
-			break
-		}
+	pinCol := input.Column("pin")
+	if pinCol == -1 {
+		return inputErrorf("CSV file must have a 'pin' or 'PIN' column")
 	}
 
-	if pinCol == -1 {
-		return fmt.Errorf("CSV file must have a 'pin' or 'PIN' column")
+	if len(input.Rows) == 0 {
+		return inputErrorf("no PINs found in CSV file")
 	}
 
-	// Read all PINs
-	pins := make([]string, 0)
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			break
+	inputs := parseBatchRows(input, func(row internal.InputRow) (string, error) {
+		pin := row.Value(pinCol)
+		if pin == "" {
+			return "", inputErrorf("missing PIN")
 		}
-
-		if pinCol < len(record) {
-			pins = append(pins, record[pinCol])
+		if pinPrecheck {
+			if check := internal.LintPIN(pin); !check.Valid {
+				return pin, invalidInputf("malformed PIN: %s", check.Reason)
+			}
 		}
+		return pin, nil
+	})
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Verifying %d PINs...\n", len(inputs))
 	}
 
-	if len(pins) == 0 {
-		return fmt.Errorf("no PINs found in CSV file")
+	spec, err := verifyPINSpec(client)
+	if err != nil {
+		return err
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Verifying %d PINs...\n", len(pins))
+	if watching() {
+		return runWatch(ctx, fmt.Sprintf("%d PINs", len(inputs)), func(ctx context.Context) (interface{}, error) {
+			return runBatch(ctx, spec, input, inputs)
+		})
 	}
 
 	// Verify all PINs
-	results, err := client.VerifyPINsBatch(ctx, pins)
+	records, err := runBatch(ctx, spec, input, inputs)
 	if err != nil {
 		return fmt.Errorf("failed to verify PINs: %w", err)
 	}
 
-	if verbose {
-		validCount := 0
-		for _, r := range results {
-			if r.IsValid {
-				validCount++
-			}
-		}
-		fmt.Fprintf(os.Stderr, "Verified %d PINs (%d valid, %d invalid)\n", len(results), validCount, len(results)-validCount)
-	}
+	return reportBatch(ctx, formatter, records)
+}
 
-	return formatter.Print(results)
+// verifyPINSpec describes a PIN verification, answered from the cache when
+// one is configured. Results are cached under the normalized PIN, so the
+// same PIN typed differently is one entry.
+func verifyPINSpec(client *kra.Client) (batchSpec[string, *kra.PINVerificationResult], error) {
+	key := func(pin string) string { return pin }
+	verify, err := cachedCall("verify-pin", internal.NormalizePIN, meteredCall("verify-pin", client.VerifyPIN))
+	if err != nil {
+		return batchSpec[string, *kra.PINVerificationResult]{}, err
+	}
+	return batchSpec[string, *kra.PINVerificationResult]{
+		Command: "verify-pin",
+		Key:     key,
+		Call:    verify,
+		Valid:   func(r *kra.PINVerificationResult) bool { return r.IsValid },
+	}, nil
 }
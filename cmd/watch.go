@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/BerjisTech/kra-cli/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval      string
+	watchOnChange      string
+	watchMetricsListen string
+)
+
+// minWatchInterval keeps watch mode from hammering the API
+const minWatchInterval = time.Minute
+
+// watchChange is a change found by watch mode, with when it was found
+type watchChange struct {
+	Time                  time.Time `json:"time"`
+	internal.RecordChange `output:"inline"`
+}
+
+// watchFetch performs one round of lookups and returns its batch records
+type watchFetch func(ctx context.Context) (interface{}, error)
+
+// addWatchFlags registers the flags of a command that can watch for changes
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&watchInterval, "watch", "", "re-check every interval (e.g. 30m, 6h, 1d) and print only what changed, until interrupted")
+	cmd.Flags().StringVar(&watchOnChange, "on-change", "", "with --watch, run this shell command with the changes as JSON on stdin instead of printing them")
+	cmd.Flags().StringVar(&watchMetricsListen, "metrics-listen", "", "with --watch, serve Prometheus metrics and health checks on this address (e.g. :9090)")
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		// A hook kept as a command default only applies when watching
+		if cmd.Flags().Changed("on-change") && !watching() {
+			return inputErrorf("--on-change requires --watch")
+		}
+		if cmd.Flags().Changed("metrics-listen") && !watching() {
+			return inputErrorf("--metrics-listen requires --watch")
+		}
+		return nil
+	}
+}
+
+// watching reports whether --watch was given
+func watching() bool {
+	return watchInterval != ""
+}
+
+// parseWatchFlags checks the watch flags and returns the interval
+func parseWatchFlags() (time.Duration, error) {
+	interval, err := internal.ParseDuration(watchInterval)
+	if err != nil {
+		return 0, inputErrorf("invalid --watch: %w", err)
+	}
+	if interval < minWatchInterval {
+		return 0, inputErrorf("--watch must be at least %s", minWatchInterval)
+	}
+	if batchCheckpoint != "" {
+		return 0, inputErrorf("--watch cannot be used with --checkpoint")
+	}
+	return interval, nil
+}
+
+// watchSingle returns a watchFetch looking up one input with spec
+func watchSingle[In, Out any](spec batchSpec[In, Out], in In) watchFetch {
+	call := historyCall(spec)
+	return func(ctx context.Context) (interface{}, error) {
+		record := internal.BatchRecord[Out]{Line: 1, Input: spec.Key(in)}
+		out, err := call(ctx, in)
+		if err != nil {
+			record.Status = internal.StatusError
+			record.Error = err.Error()
+		} else {
+			record.Status = batchStatus(spec, out)
+			record.Result = out
+		}
+		return []internal.BatchRecord[Out]{record}, nil
+	}
+}
+
+// runWatch repeats fetch every --watch interval until ctx is cancelled, and
+// prints, or passes to the --on-change hook, what changed since the previous
+// round. The first round sets the baseline, unless --compare-to gives one.
+// With --metrics-listen, metrics and health checks are served meanwhile.
+// Cached results are refreshed on every round so changes are not hidden.
+func runWatch(ctx context.Context, what string, fetch watchFetch) error {
+	interval, err := parseWatchFlags()
+	if err != nil {
+		return err
+	}
+	cacheRefresh = true
+
+	var previous []internal.ResultRecord
+	if batchCompareTo != "" {
+		if previous, err = internal.LoadResultRecords(batchCompareTo); err != nil {
+			return withExitCode(exitInput, err)
+		}
+	}
+
+	if watchMetricsListen != "" {
+		apiMetrics = internal.NewMetrics()
+		if err := startMetricsServer(ctx, watchMetricsListen); err != nil {
+			return err
+		}
+	}
+
+	formatter := internal.NewOutputFormatter(outputFmt)
+	fmt.Fprintf(os.Stderr, "Watching %s every %s (Ctrl-C to stop)\n", what, interval)
+
+	for {
+		results, err := fetch(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		var current []internal.ResultRecord
+		if err == nil {
+			current, err = watchRecords(results)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: check failed, retrying in %s: %s\n", interval, err)
+		} else {
+			if kept := keepFailedLookups(previous, current); kept > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d lookups failed; keeping their previous results\n", kept)
+			}
+			if previous != nil {
+				changes := internal.DiffResults(previous, current)
+				if err := emitChanges(ctx, formatter, what, changes); err != nil {
+					return err
+				}
+			}
+			previous = current
+		}
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Next check at %s\n", time.Now().Add(interval).Format("2006-01-02 15:04:05"))
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "✓ Stopped watching")
+	return nil
+}
+
+// watchRecords converts the results of one round into comparable records
+func watchRecords(results interface{}) ([]internal.ResultRecord, error) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	return internal.ParseResultRecords(data)
+}
+
+// keepFailedLookups replaces records whose lookup failed this round with
+// their previous result, so a transient error is not reported as a change.
+// It returns how many records were kept.
+func keepFailedLookups(previous, current []internal.ResultRecord) int {
+	byKey := make(map[string]internal.ResultRecord, len(previous))
+	for _, r := range previous {
+		byKey[r.Key] = r
+	}
+
+	kept := 0
+	for i, r := range current {
+		if r.Fields != nil {
+			continue
+		}
+		if old, ok := byKey[r.Key]; ok && old.Fields != nil {
+			current[i] = old
+			kept++
+		}
+	}
+	return kept
+}
+
+// emitChanges prints the changes of one round in what is watched, or runs
+// the --on-change hook with them, and sends the --notify notification.
+// Nothing is emitted for a round without changes.
+func emitChanges(ctx context.Context, formatter *internal.OutputFormatter, what string, changes []internal.RecordChange) error {
+	if len(changes) == 0 {
+		if verbose {
+			fmt.Fprintln(os.Stderr, "No changes")
+		}
+		return nil
+	}
+
+	notify(ctx, internal.EventChanges, fmt.Sprintf("%d changes in %s", len(changes), what), internal.ChangeItems(changes))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := make([]watchChange, len(changes))
+	for i, c := range changes {
+		rows[i] = watchChange{Time: now, RecordChange: c}
+	}
+
+	if watchOnChange == "" {
+		return formatter.Print(rows)
+	}
+
+	if err := runChangeHook(ctx, watchOnChange, rows); err != nil {
+		// A failing hook should not end the watch
+		fmt.Fprintf(os.Stderr, "Warning: --on-change command failed: %s\n", err)
+	}
+	return nil
+}
+
+// runChangeHook runs command with the shell, passing the changes as a JSON
+// array on stdin and their number in KRA_WATCH_CHANGES
+func runChangeHook(ctx context.Context, command string, rows []watchChange) error {
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+
+	var hook *exec.Cmd
+	if runtime.GOOS == "windows" {
+		hook = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		hook = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	hook.Stdin = bytes.NewReader(payload)
+	hook.Stdout = os.Stdout
+	hook.Stderr = os.Stderr
+	hook.Env = append(os.Environ(), "KRA_WATCH_CHANGES="+strconv.Itoa(len(rows)))
+	return hook.Run()
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/BerjisTech/kra-cli/internal"
+)
+
+func TestKeepFailedLookups(t *testing.T) {
+	previous := []internal.ResultRecord{
+		{Key: "P051234567A", Status: internal.StatusOK, Fields: map[string]interface{}{"is_valid": true}},
+		{Key: "P059876543B", Status: internal.StatusError, Error: "timeout"},
+	}
+	current := []internal.ResultRecord{
+		{Key: "P051234567A", Status: internal.StatusError, Error: "timeout"},
+		{Key: "P059876543B", Status: internal.StatusError, Error: "timeout"},
+		{Key: "P053333333E", Status: internal.StatusError, Error: "timeout"},
+	}
+
+	if kept := keepFailedLookups(previous, current); kept != 1 {
+		t.Fatalf("expected 1 record kept, got %d", kept)
+	}
+	if current[0].Fields == nil {
+		t.Errorf("expected the failed lookup to keep its previous result")
+	}
+	if changes := internal.DiffResults(previous, current); len(changes) != 1 || changes[0].Change != internal.ChangeAdded {
+		t.Errorf("expected only the new record to be reported, got %+v", changes)
+	}
+}
+
+func TestEmitChangesRunsHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook test uses sh")
+	}
+
+	out := filepath.Join(t.TempDir(), "changes.json")
+	watchOnChange = `cat > "` + out + `"; echo "$KRA_WATCH_CHANGES" > "` + out + `.count"`
+	defer func() { watchOnChange = "" }()
+
+	changes := []internal.RecordChange{{Key: "P051234567A", Change: internal.ChangeChanged, Field: "valid", Old: "valid", New: "invalid"}}
+	if err := emitChanges(context.Background(), internal.NewOutputFormatter("json"), "PIN P051234567A", changes); err != nil {
+		t.Fatalf("emitChanges returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if count, _ := os.ReadFile(out + ".count"); string(count) != "1\n" {
+		t.Errorf("expected KRA_WATCH_CHANGES=1, got %q", count)
+	}
+	var rows []watchChange
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("hook got invalid JSON %q: %v", data, err)
+	}
+	if len(rows) != 1 || rows[0].Key != "P051234567A" || rows[0].New != "invalid" || rows[0].Time.IsZero() {
+		t.Errorf("unexpected hook payload: %+v", rows)
+	}
+}
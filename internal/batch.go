@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchOptions controls how a batch of requests is dispatched
+type BatchOptions struct {
+	Concurrency int                   // number of parallel workers (minimum 1)
+	Rate        float64               // maximum requests per second, 0 for unlimited
+	OnProgress  func(done, total int) // optional callback invoked after each item completes
+}
+
+// BatchResult holds the outcome of a single batch item
+type BatchResult[T any] struct {
+	Index int
+	Value T
+	Err   error
+}
+
+// RunBatch processes every input with fn using a pool of workers, throttled by
+// the configured rate. Results are returned in input order. When ctx is
+// cancelled no further items are dispatched and the remaining results carry
+// the context error.
+func RunBatch[In, Out any](ctx context.Context, inputs []In, opts BatchOptions, fn func(context.Context, In) (Out, error)) []BatchResult[Out] {
+	results := make([]BatchResult[Out], len(inputs))
+	for i := range results {
+		results[i].Index = i
+	}
+	if len(inputs) == 0 {
+		return results
+	}
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+
+	limiter := NewRateLimiter(opts.Rate)
+	jobs := make(chan int)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := limiter.Wait(ctx); err != nil {
+					results[i].Err = err
+				} else {
+					results[i].Value, results[i].Err = fn(ctx, inputs[i])
+				}
+
+				if opts.OnProgress != nil {
+					mu.Lock()
+					done++
+					opts.OnProgress(done, len(inputs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(inputs); next++ {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- next:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < len(inputs); i++ {
+		results[i].Err = ctx.Err()
+	}
+
+	return results
+}
+
+// RateLimiter spaces out calls so that no more than a fixed number happen per second
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter creates a limiter allowing perSecond calls per second.
+// A non-positive rate returns nil, which never blocks.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next call is allowed or ctx is cancelled
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseRate parses a rate such as "5/s", "300/m" or "1000/h" into requests per second.
+// A bare number is interpreted as requests per second; "0" or "" disables limiting.
+func ParseRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	count, unit, found := strings.Cut(s, "/")
+	per := time.Second
+	if found {
+		switch strings.ToLower(strings.TrimSpace(unit)) {
+		case "s", "sec", "second":
+			per = time.Second
+		case "m", "min", "minute":
+			per = time.Minute
+		case "h", "hr", "hour":
+			per = time.Hour
+		default:
+			return 0, fmt.Errorf("invalid rate unit %q (use s, m or h)", unit)
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q (expected e.g. 5/s)", s)
+	}
+
+	return n / per.Seconds(), nil
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBatchPreservesOrder(t *testing.T) {
+	inputs := []int{5, 1, 4, 2, 3}
+
+	results := RunBatch(context.Background(), inputs, BatchOptions{Concurrency: 3}, func(ctx context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		return n * 10, nil
+	})
+
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("unexpected error at %d: %v", i, r.Err)
+		}
+		if r.Index != i || r.Value != inputs[i]*10 {
+			t.Fatalf("result %d = %+v, expected value %d", i, r, inputs[i]*10)
+		}
+	}
+}
+
+func TestRunBatchLimitsConcurrency(t *testing.T) {
+	var active, peak int32
+
+	RunBatch(context.Background(), make([]int, 20), BatchOptions{Concurrency: 2}, func(ctx context.Context, _ int) (struct{}, error) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return struct{}{}, nil
+	})
+
+	if peak > 2 {
+		t.Fatalf("expected at most 2 concurrent workers, saw %d", peak)
+	}
+}
+
+func TestRunBatchCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	results := RunBatch(ctx, make([]int, 10), BatchOptions{Concurrency: 1}, func(ctx context.Context, _ int) (int, error) {
+		cancel()
+		return 1, nil
+	})
+
+	if results[0].Err != nil {
+		t.Fatalf("expected first item to complete, got %v", results[0].Err)
+	}
+	if !errors.Is(results[len(results)-1].Err, context.Canceled) {
+		t.Fatalf("expected last item to be cancelled, got %v", results[len(results)-1].Err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	cases := map[string]float64{
+		"":       0,
+		"0":      0,
+		"5":      5,
+		"5/s":    5,
+		"120/m":  2,
+		"3600/h": 1,
+	}
+
+	for input, expected := range cases {
+		got, err := ParseRate(input)
+		if err != nil {
+			t.Fatalf("ParseRate(%q) returned error: %v", input, err)
+		}
+		if got != expected {
+			t.Fatalf("ParseRate(%q) = %v, expected %v", input, got, expected)
+		}
+	}
+
+	if _, err := ParseRate("5/d"); err == nil {
+		t.Fatalf("expected error for unsupported unit")
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheEntry is one cached API result
+type CacheEntry struct {
+	Endpoint string          `json:"endpoint"`
+	BaseURL  string          `json:"base_url"`
+	Key      string          `json:"key"`
+	StoredAt time.Time       `json:"stored_at"`
+	Value    json.RawMessage `json:"value"`
+
+	Path string `json:"-"`
+	Size int64  `json:"-"`
+}
+
+// Fresh reports whether the entry is younger than ttl at now
+func (e *CacheEntry) Fresh(ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && now.Sub(e.StoredAt) < ttl
+}
+
+// ResultCache stores API results on disk, one file per endpoint, API base URL
+// and identifier, so repeated lookups can skip the API while results are fresh
+type ResultCache struct {
+	dir string
+}
+
+// NewResultCache returns a cache kept in dir
+func NewResultCache(dir string) *ResultCache {
+	return &ResultCache{dir: dir}
+}
+
+// Dir returns the cache directory
+func (c *ResultCache) Dir() string {
+	return c.dir
+}
+
+// Get decodes the cached result for key into v if one younger than ttl
+// exists. Unreadable entries are treated as missing.
+func (c *ResultCache) Get(endpoint, baseURL, key string, ttl time.Duration, v interface{}) bool {
+	entry, err := readCacheEntry(c.path(endpoint, baseURL, key))
+	if err != nil || !entry.Fresh(ttl, time.Now()) || entry.Key != key {
+		return false
+	}
+	return json.Unmarshal(entry.Value, v) == nil
+}
+
+// Put stores v as the result for key
+func (c *ResultCache) Put(endpoint, baseURL, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(CacheEntry{
+		Endpoint: endpoint,
+		BaseURL:  baseURL,
+		Key:      key,
+		StoredAt: time.Now().UTC(),
+		Value:    value,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(endpoint, baseURL, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// A unique temporary file lets parallel batch workers store the same key
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns every cached result, without failing on unreadable files
+func (c *ResultCache) Entries() ([]CacheEntry, error) {
+	entries := make([]CacheEntry, 0)
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && path == c.dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		entry, err := readCacheEntry(path)
+		if err != nil {
+			// Keep unreadable files listed so prune and clear remove them
+			entry = &CacheEntry{Endpoint: filepath.Base(filepath.Dir(path)), Path: path}
+			if info, statErr := d.Info(); statErr == nil {
+				entry.Size = info.Size()
+			}
+		}
+		entries = append(entries, *entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	return entries, nil
+}
+
+// Remove deletes cached entries and returns how many were removed. Entries
+// already gone, e.g. removed by another process, are not counted.
+func (c *ResultCache) Remove(entries []CacheEntry) (int, error) {
+	removed := 0
+	for _, entry := range entries {
+		err := os.Remove(entry.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (c *ResultCache) path(endpoint, baseURL, key string) string {
+	sum := sha256.Sum256([]byte(baseURL + "\x00" + key))
+	return filepath.Join(c.dir, endpoint, hex.EncodeToString(sum[:16])+".json")
+}
+
+func readCacheEntry(path string) (*CacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	entry.Path = path
+	entry.Size = int64(len(data))
+	return &entry, nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache := NewResultCache(dir)
+
+	entries, err := cache.Entries()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty cache, got %v, %v", entries, err)
+	}
+
+	type result struct {
+		PIN     string `json:"pin"`
+		IsValid bool   `json:"is_valid"`
+	}
+
+	var got *result
+	if cache.Get("verify-pin", "https://sbx.kra.go.ke", "P051234567A", time.Hour, &got) {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	if err := cache.Put("verify-pin", "https://sbx.kra.go.ke", "P051234567A", &result{PIN: "P051234567A", IsValid: true}); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	if !cache.Get("verify-pin", "https://sbx.kra.go.ke", "P051234567A", time.Hour, &got) || got == nil || !got.IsValid {
+		t.Fatalf("expected a hit, got %+v", got)
+	}
+	if cache.Get("verify-pin", "https://api.kra.go.ke", "P051234567A", time.Hour, &got) {
+		t.Error("expected results to be kept apart per base URL")
+	}
+	if cache.Get("verify-pin", "https://sbx.kra.go.ke", "P051234567A", 0, &got) {
+		t.Error("expected a zero TTL to never hit")
+	}
+
+	// Backdate the entry to make it stale
+	entries, err = cache.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one entry, got %v, %v", entries, err)
+	}
+	stale := time.Now().Add(-2 * time.Hour)
+	entries[0].StoredAt = stale
+	if entries[0].Fresh(time.Hour, time.Now()) {
+		t.Error("expected a backdated entry to be stale")
+	}
+
+	// Corrupt files are listed so they can be removed
+	if err := os.WriteFile(filepath.Join(dir, "verify-pin", "broken.json"), []byte("{"), 0o600); err != nil {
+		t.Fatalf("failed to write corrupt entry: %v", err)
+	}
+	entries, _ = cache.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %d", len(entries))
+	}
+
+	removed, err := cache.Remove(entries)
+	if err != nil || removed != 2 {
+		t.Fatalf("Remove returned %d, %v", removed, err)
+	}
+	if removed, err := cache.Remove(entries); err != nil || removed != 0 {
+		t.Errorf("Remove of missing entries returned %d, %v", removed, err)
+	}
+	if cache.Get("verify-pin", "https://sbx.kra.go.ke", "P051234567A", time.Hour, &got) {
+		t.Error("expected a miss after removing every entry")
+	}
+}
@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secrets in recorded cassettes
+const Redacted = "REDACTED"
+
+// Cassette is a recording of HTTP interactions
+type Cassette struct {
+	Version      int           `yaml:"version"`
+	RecordedAt   time.Time     `yaml:"recorded_at"`
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  CassetteRequest  `yaml:"request"`
+	Response CassetteResponse `yaml:"response"`
+}
+
+// CassetteRequest is a recorded request
+type CassetteRequest struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response
+type CassetteResponse struct {
+	Status   int         `yaml:"status"`
+	Headers  http.Header `yaml:"headers,omitempty"`
+	Body     string      `yaml:"body,omitempty"`
+	Duration string      `yaml:"duration,omitempty"`
+}
+
+// secretHeaders are request and response headers that are always redacted
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Apikey"}
+
+// secretNameMarkers identify query parameters and JSON fields holding secrets
+var secretNameMarkers = []string{"secret", "password", "token", "apikey", "api_key", "authorization"}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := yaml.Unmarshal(raw, &cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if cassette.Version != 1 {
+		return nil, fmt.Errorf("cassette %s has unsupported version %d", path, cassette.Version)
+	}
+	return &cassette, nil
+}
+
+// Recorder passes requests through to Base and appends every interaction,
+// with secrets redacted, to the cassette at Path. The file is rewritten after
+// each interaction so it is complete however the process ends.
+type Recorder struct {
+	Base http.RoundTripper
+	Path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a recorder writing to path
+func NewRecorder(path string, base http.RoundTripper) *Recorder {
+	return &Recorder{
+		Base:     base,
+		Path:     path,
+		cassette: Cassette{Version: 1, RecordedAt: time.Now().UTC()},
+	}
+}
+
+// Save writes the cassette, creating it even when nothing was recorded
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save()
+}
+
+func (r *Recorder) save() error {
+	raw, err := yaml.Marshal(&r.cassette)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.Path, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(start)
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: redactRequest(CassetteRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: req.Header.Clone(),
+			Body:    reqBody,
+		}),
+		Response: CassetteResponse{
+			Status:   resp.StatusCode,
+			Headers:  redactHeaders(resp.Header.Clone()),
+			Body:     redactBody(respBody),
+			Duration: duration.Round(time.Millisecond).String(),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Replayer answers requests from a cassette without touching the network.
+// Each interaction is used once, in recorded order among equal requests.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer creates a replayer for cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{cassette: cassette, used: make([]bool, len(cassette.Interactions))}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	want := redactRequest(CassetteRequest{Method: req.Method, URL: req.URL.String(), Body: body})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Prefer an exact match including the body, then the first unused
+	// interaction for the same method and URL
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request.Method != want.Method || interaction.Request.URL != want.URL {
+			continue
+		}
+		if interaction.Request.Body == want.Body {
+			match = i
+			break
+		}
+		if match == -1 {
+			match = i
+		}
+	}
+	if match == -1 {
+		return nil, fmt.Errorf("replay: no recorded interaction for %s %s", req.Method, want.URL)
+	}
+	r.used[match] = true
+
+	recorded := r.cassette.Interactions[match].Response
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads a request or response body and replaces it with a re-readable copy
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	raw, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = io.NopCloser(bytes.NewReader(raw))
+	return string(raw), nil
+}
+
+// redactRequest removes secrets from a recorded request
+func redactRequest(req CassetteRequest) CassetteRequest {
+	req.Headers = redactHeaders(req.Headers)
+	req.URL = redactURL(req.URL)
+	req.Body = redactBody(req.Body)
+	return req
+}
+
+// redactHeaders replaces the values of secret headers
+func redactHeaders(header http.Header) http.Header {
+	for _, name := range secretHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, Redacted)
+		}
+	}
+	return header
+}
+
+// redactURL replaces secret query parameters
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+
+	query := u.Query()
+	changed := false
+	for name := range query {
+		if isSecretName(name) {
+			query.Set(name, Redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// redactBody replaces secret fields in a JSON body. Bodies without secrets
+// are returned unchanged, byte for byte.
+func redactBody(body string) string {
+	var value interface{}
+	if json.Unmarshal([]byte(body), &value) != nil {
+		return body
+	}
+	if !redactJSON(value) {
+		return body
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return string(raw)
+}
+
+// redactJSON redacts secret fields in place and reports whether any were found
+func redactJSON(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSecretName(key) {
+				if _, ok := item.(string); ok {
+					v[key] = Redacted
+					changed = true
+					continue
+				}
+			}
+			if redactJSON(item) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactJSON(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// isSecretName reports whether a parameter or field name looks like it holds a secret
+func isSecretName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, marker := range secretNameMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
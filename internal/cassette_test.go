@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			io.WriteString(w, `{"access_token":"live-token-123","expires_in":"3599"}`)
+			return
+		}
+		io.WriteString(w, `{"pin_number": "P051234567A", "is_valid": true}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	recorder := NewRecorder(path, http.DefaultTransport)
+	client := &http.Client{Transport: recorder}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/token?grant_type=client_credentials", nil)
+	req.SetBasicAuth("id", "super-secret")
+	if _, err := client.Do(req); err != nil {
+		t.Fatalf("token request failed: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/pin", strings.NewReader(`{"KRAPIN":"P051234567A"}`))
+	req.Header.Set("Authorization", "Bearer live-token-123")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("pin request failed: %v", err)
+	}
+	live, _ := io.ReadAll(resp.Body)
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cassette not written: %v", err)
+	}
+	for _, secret := range []string{"live-token-123", "super-secret", "aWQ6c3VwZXItc2VjcmV0"} {
+		if strings.Contains(string(raw), secret) {
+			t.Fatalf("cassette contains secret %q:\n%s", secret, raw)
+		}
+	}
+
+	server.Close()
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette returned error: %v", err)
+	}
+	replay := &http.Client{Transport: NewReplayer(cassette)}
+
+	resp, err = replay.Post(server.URL+"/pin", "application/json", strings.NewReader(`{"KRAPIN":"P051234567A"}`))
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	if string(replayed) != string(live) {
+		t.Fatalf("replayed body %q differs from recorded %q", replayed, live)
+	}
+
+	if _, err := replay.Post(server.URL+"/pin", "application/json", nil); err == nil {
+		t.Fatalf("expected an error once the recorded interaction is used up")
+	}
+}
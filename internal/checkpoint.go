@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// checkpointHeader is the first line of every checkpoint journal
+type checkpointHeader struct {
+	Version int    `json:"version"`
+	Command string `json:"command"`
+}
+
+// checkpointEntry is a single completed item in a checkpoint journal
+type checkpointEntry struct {
+	Key    string          `json:"key"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Checkpoint is an append-only journal of completed batch items, used to
+// resume an interrupted batch run without repeating finished work
+type Checkpoint struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	entries map[string]json.RawMessage
+}
+
+// OpenCheckpoint opens the journal at path for the given command. When resume
+// is true, previously recorded entries are loaded; otherwise the file must not
+// already contain entries, to avoid silently discarding an earlier run.
+func OpenCheckpoint(path, command string, resume bool) (*Checkpoint, error) {
+	cp := &Checkpoint{entries: make(map[string]json.RawMessage)}
+
+	info, err := os.Stat(path)
+	exists := err == nil && info.Size() > 0
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	if exists && !resume {
+		return nil, fmt.Errorf("checkpoint %s already exists; use --resume to continue it or remove the file", path)
+	}
+
+	if exists {
+		if err := cp.load(path, command); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	cp.file = file
+	cp.writer = bufio.NewWriter(file)
+
+	if !exists {
+		if err := cp.writeLine(checkpointHeader{Version: 1, Command: command}); err != nil {
+			file.Close()
+			return nil, err
+		}
+	} else if !endsWithNewline(path, info.Size()) {
+		// Terminate a partial line left by a crash so new entries start cleanly
+		if _, err := file.WriteString("\n"); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+	}
+
+	return cp, nil
+}
+
+// load reads existing entries from the journal. A truncated final line, as
+// left behind by a crash mid-write, is ignored.
+func (c *Checkpoint) load(path, command string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		return fmt.Errorf("checkpoint %s is empty or unreadable", path)
+	}
+	var header checkpointHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version == 0 {
+		return fmt.Errorf("checkpoint %s has an invalid header", path)
+	}
+	if header.Command != command {
+		return fmt.Errorf("checkpoint %s was written by %s, not %s", path, header.Command, command)
+	}
+
+	for scanner.Scan() {
+		var entry checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		c.entries[entry.Key] = entry.Result
+	}
+
+	return scanner.Err()
+}
+
+// endsWithNewline reports whether the file at path of the given size ends in '\n'
+func endsWithNewline(path string, size int64) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return false
+	}
+	return last[0] == '\n'
+}
+
+// Len returns the number of completed items recorded in the journal
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Lookup decodes the recorded result for key into v, reporting whether it was found
+func (c *Checkpoint) Lookup(key string, v interface{}) (bool, error) {
+	c.mu.Lock()
+	raw, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("failed to decode checkpoint entry %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// Record appends the result for key to the journal and flushes it to disk
+func (c *Checkpoint) Record(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint entry %s: %w", key, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = raw
+	return c.writeLine(checkpointEntry{Key: key, Result: raw})
+}
+
+// writeLine encodes v as a single JSON line and flushes it; callers hold c.mu
+// or have exclusive access to the checkpoint
+func (c *Checkpoint) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := c.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the journal file
+func (c *Checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writer.Flush(); err != nil {
+		c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type checkpointResult struct {
+	PIN   string `json:"pin"`
+	Valid bool   `json:"valid"`
+}
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.journal")
+
+	cp, err := OpenCheckpoint(path, "verify-pin", false)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint returned error: %v", err)
+	}
+	if err := cp.Record("P051234567A", &checkpointResult{PIN: "P051234567A", Valid: true}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// Simulate a crash that left a partial line behind
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	f.WriteString(`{"key":"P0598`)
+	f.Close()
+
+	if _, err := OpenCheckpoint(path, "verify-pin", false); err == nil {
+		t.Fatalf("expected error when reopening an existing journal without resume")
+	}
+	if _, err := OpenCheckpoint(path, "check-tcc", true); err == nil {
+		t.Fatalf("expected error when resuming a journal from another command")
+	}
+
+	cp, err = OpenCheckpoint(path, "verify-pin", true)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint(resume) returned error: %v", err)
+	}
+
+	if cp.Len() != 1 {
+		t.Fatalf("expected 1 recorded entry, got %d", cp.Len())
+	}
+
+	var got *checkpointResult
+	found, err := cp.Lookup("P051234567A", &got)
+	if err != nil || !found {
+		t.Fatalf("Lookup = %v, %v; expected recorded entry", found, err)
+	}
+	if got.PIN != "P051234567A" || !got.Valid {
+		t.Fatalf("unexpected recorded result: %+v", got)
+	}
+
+	if found, _ := cp.Lookup("P059876543B", &got); found {
+		t.Fatalf("expected unknown key to be missing")
+	}
+
+	if err := cp.Record("P059876543B", &checkpointResult{PIN: "P059876543B"}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	cp.Close()
+
+	cp, err = OpenCheckpoint(path, "verify-pin", true)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint(resume) returned error: %v", err)
+	}
+	if cp.Len() != 2 {
+		t.Fatalf("expected 2 recorded entries after partial line, got %d", cp.Len())
+	}
+	cp.Close()
+}
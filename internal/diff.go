@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Record change kinds
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ResultRecord is one record of a saved JSON result file: a batch record or
+// a single command result, identified by its PIN, TCC or e-slip number
+type ResultRecord struct {
+	Key    string
+	Status string // batch status; empty for single results
+	Error  string
+	Fields map[string]interface{} // the API result; nil when the lookup failed
+}
+
+// RecordChange is one difference between two runs of the same records
+type RecordChange struct {
+	Key    string `json:"key"`
+	Change string `json:"change"`
+	Field  string `json:"field,omitempty"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// resultKeyFields are the result fields identifying a single result
+var resultKeyFields = []string{"pin_number", "kra_pin", "pin", "tcc_number", "eslip_number"}
+
+// compareFields are the scalar result fields compared between runs, by the
+// name they are reported under
+var compareFields = []struct {
+	name    string
+	aliases []string
+}{
+	{"status", []string{"status", "taxpayer_status"}},
+	{"name", []string{"taxpayer_name", "name", "business_name"}},
+	{"type", []string{"taxpayer_type"}},
+	{"expiry", []string{"expiry_date"}},
+}
+
+// LoadResultRecords reads a JSON file written with --output json by a batch
+// command or a single lookup
+func LoadResultRecords(path string) ([]ResultRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	records, err := ParseResultRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid results file %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// ParseResultRecords parses JSON results: an array of batch records or
+// results, or a single result object
+func ParseResultRecords(data []byte) ([]ResultRecord, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		items = []interface{}{v}
+	default:
+		return nil, fmt.Errorf("expected a JSON array or object")
+	}
+
+	records := make([]ResultRecord, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("record %d is not a JSON object", i+1)
+		}
+
+		var record ResultRecord
+		if isBatchRecord(m) {
+			record.Key = fmt.Sprint(m["input"])
+			record.Status = fmt.Sprint(m["status"])
+			if e, ok := m["error"].(string); ok {
+				record.Error = e
+			}
+			record.Fields, _ = m["result"].(map[string]interface{})
+		} else {
+			record.Key = LookupField(m, resultKeyFields...)
+			record.Fields = m
+		}
+
+		record.Key = strings.ToUpper(strings.TrimSpace(record.Key))
+		if record.Key == "" {
+			return nil, fmt.Errorf("record %d has no PIN, TCC or e-slip number", i+1)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// isBatchRecord reports whether m has the shape of a BatchRecord
+func isBatchRecord(m map[string]interface{}) bool {
+	_, hasInput := m["input"]
+	_, hasStatus := m["status"]
+	_, hasLine := m["line"]
+	return hasInput && hasStatus && hasLine
+}
+
+// DiffResults compares two runs of the same records, matched by key, and
+// returns what changed: records added or removed, lookups that started or
+// stopped failing, validity, status, name, type and expiry changes, and
+// obligations added or removed. Changes are ordered as the records appear in
+// the new run, followed by removed records.
+func DiffResults(old, new []ResultRecord) []RecordChange {
+	oldByKey := make(map[string]ResultRecord, len(old))
+	for _, r := range old {
+		if _, seen := oldByKey[r.Key]; !seen {
+			oldByKey[r.Key] = r
+		}
+	}
+
+	changes := make([]RecordChange, 0)
+	seen := make(map[string]bool, len(new))
+	for _, n := range new {
+		if seen[n.Key] {
+			continue
+		}
+		seen[n.Key] = true
+
+		o, ok := oldByKey[n.Key]
+		if !ok {
+			changes = append(changes, RecordChange{Key: n.Key, Change: ChangeAdded, New: n.outcome()})
+			continue
+		}
+		changes = append(changes, diffRecord(o, n)...)
+	}
+
+	for _, o := range old {
+		if !seen[o.Key] {
+			seen[o.Key] = true
+			changes = append(changes, RecordChange{Key: o.Key, Change: ChangeRemoved, Old: o.outcome()})
+		}
+	}
+	return changes
+}
+
+// diffRecord compares two runs of one record
+func diffRecord(o, n ResultRecord) []RecordChange {
+	changes := make([]RecordChange, 0)
+	add := func(field, oldValue, newValue string) {
+		if !strings.EqualFold(oldValue, newValue) {
+			changes = append(changes, RecordChange{Key: n.Key, Change: ChangeChanged, Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	// A failed lookup says nothing about the record, so only report it
+	if o.Fields == nil || n.Fields == nil {
+		add("lookup", o.outcome(), n.outcome())
+		return changes
+	}
+
+	add("valid", o.validity(), n.validity())
+	for _, f := range compareFields {
+		add(f.name, LookupField(o.Fields, f.aliases...), LookupField(n.Fields, f.aliases...))
+	}
+
+	oldObligations := obligationSet(o.Fields)
+	newObligations := obligationSet(n.Fields)
+	for _, code := range sortedKeys(newObligations) {
+		if _, ok := oldObligations[code]; !ok {
+			changes = append(changes, RecordChange{Key: n.Key, Change: ChangeAdded, Field: "obligation", New: newObligations[code]})
+		}
+	}
+	for _, code := range sortedKeys(oldObligations) {
+		if _, ok := newObligations[code]; !ok {
+			changes = append(changes, RecordChange{Key: n.Key, Change: ChangeRemoved, Field: "obligation", Old: oldObligations[code]})
+		}
+	}
+	return changes
+}
+
+// outcome summarises a record as valid, invalid, or why its lookup failed
+func (r *ResultRecord) outcome() string {
+	if r.Fields == nil {
+		if r.Error != "" {
+			return r.Status + ": " + r.Error
+		}
+		return r.Status
+	}
+	return r.validity()
+}
+
+// validity reports a result as valid or invalid, from its is_valid field or
+// else its batch status; results with neither, such as taxpayer details,
+// report nothing
+func (r *ResultRecord) validity() string {
+	switch strings.ToLower(LookupField(r.Fields, "is_valid", "valid")) {
+	case "true":
+		return "valid"
+	case "false":
+		return "invalid"
+	}
+	switch r.Status {
+	case StatusOK:
+		return "valid"
+	case StatusInvalid:
+		return "invalid"
+	}
+	return ""
+}
+
+// obligationSet returns the obligations of a taxpayer result by code, each
+// described by its code and name
+func obligationSet(fields map[string]interface{}) map[string]string {
+	set := make(map[string]string)
+	for key, value := range fields {
+		if normalizeFieldName(key) != "obligations" {
+			continue
+		}
+		items, _ := value.([]interface{})
+		for _, item := range items {
+			code := LookupField(item, "obligation_code", "code")
+			if code == "" {
+				continue
+			}
+			description := code
+			if name := LookupField(item, "description", "obligation_name", "name"); name != "" {
+				description += " (" + name + ")"
+			}
+			set[code] = description
+		}
+	}
+	return set
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	old, err := ParseResultRecords([]byte(`[
+		{"line": 2, "input": "P051234567A", "status": "ok", "result": {"pin_number": "P051234567A", "is_valid": true, "taxpayer_name": "ACME LTD"}},
+		{"line": 3, "input": "P059876543B", "status": "ok", "result": {"pin_number": "P059876543B", "is_valid": true}},
+		{"line": 4, "input": "P051111111C", "status": "error", "error": "timeout"},
+		{"line": 5, "input": "P052222222D", "status": "ok", "result": {"pin_number": "P052222222D", "is_valid": true}}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseResultRecords returned error: %v", err)
+	}
+	new, err := ParseResultRecords([]byte(`[
+		{"line": 2, "input": "p051234567a", "status": "ok", "result": {"pin_number": "P051234567A", "is_valid": true, "taxpayer_name": "ACME LIMITED"}},
+		{"line": 3, "input": "P059876543B", "status": "invalid", "result": {"pin_number": "P059876543B", "is_valid": false}},
+		{"line": 4, "input": "P051111111C", "status": "ok", "result": {"pin_number": "P051111111C", "is_valid": true}},
+		{"line": 5, "input": "P053333333E", "status": "invalid", "result": {"pin_number": "P053333333E", "is_valid": false}}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseResultRecords returned error: %v", err)
+	}
+
+	want := []RecordChange{
+		{Key: "P051234567A", Change: ChangeChanged, Field: "name", Old: "ACME LTD", New: "ACME LIMITED"},
+		{Key: "P059876543B", Change: ChangeChanged, Field: "valid", Old: "valid", New: "invalid"},
+		{Key: "P051111111C", Change: ChangeChanged, Field: "lookup", Old: "error: timeout", New: "valid"},
+		{Key: "P053333333E", Change: ChangeAdded, New: "invalid"},
+		{Key: "P052222222D", Change: ChangeRemoved, Old: "valid"},
+	}
+	if got := DiffResults(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResults =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiffResultsTaxpayerDetails(t *testing.T) {
+	old, err := ParseResultRecords([]byte(`{"pin_number": "P051234567A", "status": "Active",
+		"obligations": [{"obligation_code": 4, "description": "Income Tax - PAYE"}, {"obligation_code": 9, "description": "VAT"}]}`))
+	if err != nil {
+		t.Fatalf("ParseResultRecords returned error: %v", err)
+	}
+	new, err := ParseResultRecords([]byte(`{"pin_number": "P051234567A", "status": "Inactive",
+		"obligations": [{"obligation_code": 9, "description": "VAT"}, {"obligation_code": 7, "description": "Turnover Tax"}]}`))
+	if err != nil {
+		t.Fatalf("ParseResultRecords returned error: %v", err)
+	}
+
+	want := []RecordChange{
+		{Key: "P051234567A", Change: ChangeChanged, Field: "status", Old: "Active", New: "Inactive"},
+		{Key: "P051234567A", Change: ChangeAdded, Field: "obligation", New: "7 (Turnover Tax)"},
+		{Key: "P051234567A", Change: ChangeRemoved, Field: "obligation", Old: "4 (Income Tax - PAYE)"},
+	}
+	if got := DiffResults(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResults =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseResultRecordsRequiresKey(t *testing.T) {
+	if _, err := ParseResultRecords([]byte(`[{"is_valid": true}]`)); err == nil {
+		t.Error("expected an error for a record without a PIN, TCC or e-slip number")
+	}
+	if _, err := ParseResultRecords([]byte(`"text"`)); err == nil {
+		t.Error("expected an error for a JSON string")
+	}
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"6h":  6 * time.Hour,
+		"90m": 90 * time.Minute,
+		"0d":  0,
+	}
+	for input, want := range tests {
+		got, err := ParseDuration(input)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) returned error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseDuration(%q) = %s, want %s", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "d", "-3d", "1.5d", "soon"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) expected an error", input)
+		}
+	}
+}
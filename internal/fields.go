@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LookupField returns the first non-empty top-level field of v, an API result,
+// whose JSON name matches one of names. Names are compared ignoring case and
+// underscores, so "reference_number" also matches "referenceNumber". This
+// reads values the SDK result types carry without depending on their Go
+// field names.
+func LookupField(v interface{}, names ...string) string {
+	fields := resultFields(v)
+	for _, name := range names {
+		want := normalizeFieldName(name)
+		for key, value := range fields {
+			if normalizeFieldName(key) != want || value == nil {
+				continue
+			}
+			if s := fmt.Sprintf("%v", value); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// resultFields converts a result into a map of its JSON fields
+func resultFields(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
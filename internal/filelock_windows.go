@@ -0,0 +1,26 @@
+//go:build windows
+
+package internal
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows locks are mandatory for the bytes they cover, so the lock is taken
+// on a byte far past the end of the file, where nobody reads or writes
+const lockOffset = ^uint32(0)
+
+// lockFile takes an exclusive lock on f, waiting for other processes
+// holding it
+func lockFile(f *os.File) error {
+	ol := &windows.Overlapped{Offset: lockOffset, OffsetHigh: lockOffset}
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, ol)
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(f *os.File) error {
+	ol := &windows.Overlapped{Offset: lockOffset, OffsetHigh: lockOffset}
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, ol)
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Gateway error codes, returned in the "code" field of JSON error bodies
+const (
+	GatewayBadRequest      = "bad_request"
+	GatewayUnauthorized    = "unauthorized"
+	GatewayNotFound        = "not_found"
+	GatewayConflict        = "conflict"
+	GatewayUpstreamAuth    = "upstream_auth"
+	GatewayUpstreamTimeout = "upstream_timeout"
+	GatewayUpstreamError   = "upstream_error"
+)
+
+// GatewayError is the JSON body of a failed gateway request
+type GatewayError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *GatewayError) Error() string { return e.Message }
+
+// WriteJSON writes v as the JSON response body with status
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteGatewayError writes err as a JSON error body:
+// {"error": {"code": "...", "message": "..."}}
+func WriteGatewayError(w http.ResponseWriter, err *GatewayError) {
+	WriteJSON(w, err.Status, map[string]*GatewayError{"error": err})
+}
+
+// RequireAPIKey only passes requests carrying one of keys, as a bearer token
+// or in the X-API-Key header, to next
+func RequireAPIKey(keys []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validAPIKey(requestAPIKey(r), keys) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kra-cli"`)
+			WriteGatewayError(w, &GatewayError{Status: http.StatusUnauthorized, Code: GatewayUnauthorized, Message: "missing or invalid API key"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAPIKey returns the API key a request was made with
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// validAPIKey compares key with every configured key in constant time
+func validAPIKey(key string, keys []string) bool {
+	valid := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 && key != "" {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// LogRequests writes one line per request to log: method, path, status and
+// duration
+func LogRequests(log io.Writer, next http.Handler) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		mu.Lock()
+		fmt.Fprintf(log, "%s %s -> %d %s\n", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+		mu.Unlock()
+	})
+}
+
+// statusRecorder remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// RateLimitTransport spaces out outgoing requests with a RateLimiter, so
+// every API call made through it shares one rate however many callers there
+// are. Results answered from a cache never reach it.
+type RateLimitTransport struct {
+	Base    http.RoundTripper
+	Limiter *RateLimiter
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.Limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.Base.RoundTrip(req)
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	handler := RequireAPIKey([]string{"erp-key", "reports-key"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		header, value string
+		want          int
+	}{
+		{"Authorization", "Bearer erp-key", http.StatusNoContent},
+		{"Authorization", "bearer reports-key", http.StatusNoContent},
+		{"X-API-Key", "reports-key", http.StatusNoContent},
+		{"Authorization", "Bearer wrong", http.StatusUnauthorized},
+		{"Authorization", "Basic erp-key", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/pins/P051234567A", nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: %q got status %d, expected %d", c.header, c.value, rec.Code, c.want)
+		}
+	}
+}
+
+func TestWriteGatewayError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteGatewayError(rec, &GatewayError{Status: http.StatusConflict, Code: GatewayConflict, Message: "already filed"})
+
+	if rec.Code != http.StatusConflict || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got status %d and content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body struct {
+		Error GatewayError `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body is not JSON: %v", err)
+	}
+	if body.Error.Code != GatewayConflict || body.Error.Message != "already filed" {
+		t.Errorf("unexpected error body: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	historyEntriesBucket = []byte("entries") // id -> HistoryEntry
+	historyPINsBucket    = []byte("pins")    // PIN \x00 id -> nothing
+)
+
+// historyLockTimeout is how long to wait for another process holding the
+// history database
+const historyLockTimeout = 5 * time.Second
+
+// HistoryEntry is one API call recorded in the history database
+type HistoryEntry struct {
+	ID         uint64          `json:"id"`
+	Time       time.Time       `json:"time"`
+	Command    string          `json:"command"`
+	Input      string          `json:"input"`
+	PIN        string          `json:"pin,omitempty"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	Profile    string          `json:"profile"`
+	Run        string          `json:"run"`                         // shared by the calls of one invocation
+	Invocation string          `json:"invocation"`                  // the command line, secrets redacted
+	Result     json.RawMessage `json:"result,omitempty" output:"-"` // the API response as returned
+}
+
+// HistoryQuery selects history entries; zero fields match everything
+type HistoryQuery struct {
+	PIN     string
+	Command string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+func (q *HistoryQuery) matches(e *HistoryEntry) bool {
+	if q.PIN != "" && !strings.EqualFold(e.PIN, q.PIN) {
+		return false
+	}
+	if q.Command != "" && e.Command != q.Command {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// History is an embedded database of every API call made, kept so past
+// answers can be looked up later. Entries are only ever added.
+type History struct {
+	db *bolt.DB
+}
+
+// OpenHistory opens or creates the history database at path for writing.
+// A writer locks out every other process until it closes the database, so
+// it should be closed as soon as the write is done; others wait briefly,
+// then fail.
+func OpenHistory(path string) (*History, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	db, err := openHistoryDB(path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Creating the buckets costs a disk sync; skip it once they exist
+	var ready bool
+	db.View(func(tx *bolt.Tx) error {
+		ready = tx.Bucket(historyEntriesBucket) != nil && tx.Bucket(historyPINsBucket) != nil
+		return nil
+	})
+	if !ready {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{historyEntriesBucket, historyPINsBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open history database: %w", err)
+		}
+	}
+
+	return &History{db: db}, nil
+}
+
+// OpenHistoryReadOnly opens the history database at path for reading,
+// creating it first if it does not exist. Readers share the database with
+// each other but not with a writer.
+func OpenHistoryReadOnly(path string) (*History, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		created, err := OpenHistory(path)
+		if err != nil {
+			return nil, err
+		}
+		created.Close()
+	}
+
+	db, err := openHistoryDB(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return &History{db: db}, nil
+}
+
+// openHistoryDB opens the database file, waiting up to historyLockTimeout for
+// another process to release it
+func openHistoryDB(path string, readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: historyLockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("history database %s is in use by another kra-cli process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	return db, nil
+}
+
+// Path returns the database file location
+func (h *History) Path() string {
+	return h.db.Path()
+}
+
+// Record adds an entry, assigning its ID and, if unset, its time
+func (h *History) Record(entry HistoryEntry) (HistoryEntry, error) {
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		return putHistoryEntry(tx, &entry)
+	})
+	if err != nil {
+		return entry, fmt.Errorf("failed to write history: %w", err)
+	}
+	return entry, nil
+}
+
+// RecordAll adds entries in a single transaction, so a batch costs one disk
+// sync. Either every entry is added or none is.
+func (h *History) RecordAll(entries []HistoryEntry) error {
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		for i := range entries {
+			if err := putHistoryEntry(tx, &entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// putHistoryEntry adds entry within tx, assigning its ID and, if unset, its
+// time
+func putHistoryEntry(tx *bolt.Tx, entry *HistoryEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC().Truncate(time.Second)
+	}
+	entry.PIN = strings.ToUpper(entry.PIN)
+
+	entries := tx.Bucket(historyEntriesBucket)
+	id, err := entries.NextSequence()
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := entries.Put(historyKey(id), data); err != nil {
+		return err
+	}
+	if entry.PIN != "" {
+		return tx.Bucket(historyPINsBucket).Put(historyPINKey(entry.PIN, id), nil)
+	}
+	return nil
+}
+
+// Get returns the entry with id
+func (h *History) Get(id uint64) (HistoryEntry, bool, error) {
+	var entry HistoryEntry
+	found := false
+	err := h.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(historyEntriesBucket).Get(historyKey(id))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		return entry, false, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entry, found, nil
+}
+
+// Search returns the entries matching q, newest first. Entries are stored in
+// the order they were made, so the scan stops at the first entry older than
+// q.Since.
+func (h *History) Search(q HistoryQuery) ([]HistoryEntry, error) {
+	results := make([]HistoryEntry, 0)
+
+	// collect decodes one entry and reports whether the scan should go on
+	collect := func(data []byte) (bool, error) {
+		var entry HistoryEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return false, err
+		}
+		if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+			return false, nil
+		}
+		if q.matches(&entry) {
+			results = append(results, entry)
+		}
+		return q.Limit <= 0 || len(results) < q.Limit, nil
+	}
+
+	err := h.db.View(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(historyEntriesBucket)
+
+		if q.PIN == "" {
+			c := entries.Cursor()
+			for k, v := c.Last(); k != nil; k, v = c.Prev() {
+				more, err := collect(v)
+				if err != nil || !more {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// Walk the PIN index backwards from the end of the PIN's keys
+		pin := strings.ToUpper(q.PIN)
+		prefix := append([]byte(pin), 0)
+		c := tx.Bucket(historyPINsBucket).Cursor()
+		k, _ := c.Seek(historyPINKey(pin, ^uint64(0)))
+		if k == nil {
+			k, _ = c.Last()
+		}
+		for ; k != nil; k, _ = c.Prev() {
+			if !bytes.HasPrefix(k, prefix) {
+				if bytes.Compare(k, prefix) < 0 {
+					break
+				}
+				continue
+			}
+			data := entries.Get(k[len(prefix):])
+			if data == nil {
+				continue
+			}
+			more, err := collect(data)
+			if err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return results, nil
+}
+
+// Close closes the database
+func (h *History) Close() error {
+	return h.db.Close()
+}
+
+func historyKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+func historyPINKey(pin string, id uint64) []byte {
+	return append(append([]byte(pin), 0), historyKey(id)...)
+}
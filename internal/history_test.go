@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistorySearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "default.db")
+	history, err := OpenHistory(path)
+	if err != nil {
+		t.Fatalf("OpenHistory returned error: %v", err)
+	}
+
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []HistoryEntry{
+		{Command: "verify-pin", Input: "P051234567A", PIN: "P051234567A", Status: StatusOK},
+		{Command: "verify-pin", Input: "P059876543B", PIN: "P059876543B", Status: StatusInvalid},
+		{Command: "check-tcc", Input: "p051234567a/TCC1", PIN: "p051234567a", Status: StatusOK},
+		{Command: "validate-slip", Input: "1234567890", Status: StatusError, Error: "timeout"},
+		{Command: "verify-pin", Input: "P051234567A", PIN: "P051234567A", Status: StatusOK},
+	}
+	for i, entry := range entries {
+		entry.Time = base.Add(time.Duration(i) * 24 * time.Hour)
+		recorded, err := history.Record(entry)
+		if err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+		if recorded.ID != uint64(i+1) {
+			t.Fatalf("entry %d got ID %d", i, recorded.ID)
+		}
+	}
+	history.Close()
+
+	history, err = OpenHistory(path)
+	if err != nil {
+		t.Fatalf("reopening history returned error: %v", err)
+	}
+	defer history.Close()
+
+	ids := func(entries []HistoryEntry) string {
+		parts := make([]string, len(entries))
+		for i, e := range entries {
+			parts[i] = string(rune('0' + e.ID))
+		}
+		return strings.Join(parts, ",")
+	}
+
+	tests := []struct {
+		name  string
+		query HistoryQuery
+		want  string
+	}{
+		{"all newest first", HistoryQuery{}, "5,4,3,2,1"},
+		{"by PIN", HistoryQuery{PIN: "p051234567a"}, "5,3,1"},
+		{"by PIN since", HistoryQuery{PIN: "P051234567A", Since: base.Add(36 * time.Hour)}, "5,3"},
+		{"by PIN and command", HistoryQuery{PIN: "P051234567A", Command: "check-tcc"}, "3"},
+		{"until", HistoryQuery{Until: base.Add(2 * 24 * time.Hour)}, "2,1"},
+		{"limit", HistoryQuery{Limit: 2}, "5,4"},
+		{"other PIN", HistoryQuery{PIN: "P059876543B"}, "2"},
+		{"unknown PIN", HistoryQuery{PIN: "P000000000Z"}, ""},
+	}
+	for _, tt := range tests {
+		results, err := history.Search(tt.query)
+		if err != nil {
+			t.Fatalf("%s: Search returned error: %v", tt.name, err)
+		}
+		if got := ids(results); got != tt.want {
+			t.Errorf("%s: got entries %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	entry, ok, err := history.Get(4)
+	if err != nil || !ok || entry.Error != "timeout" {
+		t.Fatalf("Get(4) = %+v, %v, %v", entry, ok, err)
+	}
+	if _, ok, _ := history.Get(99); ok {
+		t.Fatalf("Get(99) found an entry")
+	}
+}
+
+func TestHistoryReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "default.db")
+
+	// Reading a history never written finds it empty
+	empty, err := OpenHistoryReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryReadOnly returned error: %v", err)
+	}
+	if entries, err := empty.Search(HistoryQuery{}); err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty history, got %v, %v", entries, err)
+	}
+	empty.Close()
+
+	writer, err := OpenHistory(path)
+	if err != nil {
+		t.Fatalf("OpenHistory returned error: %v", err)
+	}
+	if _, err := writer.Record(HistoryEntry{Command: "verify-pin", Input: "P051234567A", PIN: "P051234567A", Status: StatusOK}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	writer.Close()
+
+	// Readers can share the database
+	first, err := OpenHistoryReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryReadOnly returned error: %v", err)
+	}
+	defer first.Close()
+	second, err := OpenHistoryReadOnly(path)
+	if err != nil {
+		t.Fatalf("second OpenHistoryReadOnly returned error: %v", err)
+	}
+	defer second.Close()
+
+	if entry, ok, err := second.Get(1); err != nil || !ok || entry.PIN != "P051234567A" {
+		t.Errorf("expected entry 1 to be readable, got %+v, %v, %v", entry, ok, err)
+	}
+	if _, err := second.Record(HistoryEntry{Command: "verify-pin"}); err == nil {
+		t.Error("expected a read-only history to refuse writes")
+	}
+}
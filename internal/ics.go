@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarEvent is an all-day calendar event with reminders
+type CalendarEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Reminders   []time.Duration // how long before the event each reminder fires
+}
+
+// WriteICS writes events as an iCalendar (RFC 5545) document named name.
+// stamp is recorded as the creation time of every event.
+func WriteICS(w io.Writer, name string, events []CalendarEvent, stamp time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//BerjisTech//kra-cli//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icsText(name),
+	}
+
+	for _, e := range events {
+		day := time.Date(e.Date.Year(), e.Date.Month(), e.Date.Day(), 0, 0, 0, 0, time.UTC)
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+e.UID,
+			"DTSTAMP:"+stamp.UTC().Format("20060102T150405Z"),
+			"DTSTART;VALUE=DATE:"+day.Format("20060102"),
+			"DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+icsText(e.Summary),
+			"DESCRIPTION:"+icsText(e.Description),
+			"TRANSP:TRANSPARENT",
+		)
+		for _, before := range e.Reminders {
+			lines = append(lines,
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"DESCRIPTION:"+icsText(e.Summary),
+				"TRIGGER:"+icsTrigger(before),
+				"END:VALARM",
+			)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, icsFold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// icsText escapes a TEXT property value
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsTrigger formats a reminder offset as a negative iCalendar duration
+func icsTrigger(before time.Duration) string {
+	switch {
+	case before%(24*time.Hour) == 0:
+		return fmt.Sprintf("-P%dD", before/(24*time.Hour))
+	case before%time.Hour == 0:
+		return fmt.Sprintf("-PT%dH", before/time.Hour)
+	default:
+		return fmt.Sprintf("-PT%dM", before/time.Minute)
+	}
+}
+
+// icsFold splits a content line into 75-octet pieces, continuing each piece
+// on a new line that starts with a space, without splitting UTF-8 characters
+func icsFold(line string) string {
+	const limit = 75
+	var b strings.Builder
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		width = limit - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
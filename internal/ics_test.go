@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteICS(t *testing.T) {
+	events := []CalendarEvent{{
+		UID:         "TCC123@kra-cli",
+		Date:        time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		Summary:     "TCC expires: Acme, Ltd",
+		Description: strings.Repeat("Long description; ", 8),
+		Reminders:   []time.Duration{30 * 24 * time.Hour, 6 * time.Hour},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, "Supplier TCCs", events, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteICS returned error: %v", err)
+	}
+	ics := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTAMP:20240102T030405Z\r\n",
+		"DTSTART;VALUE=DATE:20240630\r\n",
+		"DTEND;VALUE=DATE:20240701\r\n",
+		"SUMMARY:TCC expires: Acme\\, Ltd\r\n",
+		"TRIGGER:-P30D\r\n",
+		"TRIGGER:-PT6H\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("expected %q in calendar:\n%s", want, ics)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	if !strings.Contains(ics, "\r\n ") {
+		t.Error("expected the long description to be folded")
+	}
+}
+
+func TestICSFoldKeepsUTF8(t *testing.T) {
+	folded := icsFold(strings.Repeat("é", 60))
+	for _, part := range strings.Split(folded, "\r\n ") {
+		if !utf8.ValidString(part) {
+			t.Fatalf("fold split a UTF-8 character: %q", part)
+		}
+	}
+}
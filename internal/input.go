@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// InputRow is a single data row of a batch CSV file
+type InputRow struct {
+	Line   int      // 1-based line number in the source file
+	Fields []string // raw field values; nil if the row could not be parsed
+	Raw    string   // source text of a row that could not be parsed
+	Err    error    // parse error for this row, if any
+}
+
+// Value returns the trimmed field at col, or "" if the row is too short
+func (r InputRow) Value(col int) string {
+	if col < 0 || col >= len(r.Fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.Fields[col])
+}
+
+// CSVInput is a batch CSV file with its header and every data row, including
+// malformed ones, so that no input line is silently dropped
+type CSVInput struct {
+	Header []string
+	Rows   []InputRow
+}
+
+// ReadCSVInput reads a batch CSV file. Rows that fail to parse are kept with
+// their error and line number instead of stopping the read.
+func ReadCSVInput(path string) (*CSVInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch file: %w", err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	input := &CSVInput{Header: header}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			input.Rows = append(input.Rows, InputRow{Line: parseErr.StartLine, Raw: sourceLines(lines, parseErr.StartLine, parseErr.Line), Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch file: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		input.Rows = append(input.Rows, InputRow{Line: line, Fields: record})
+	}
+
+	return input, nil
+}
+
+// Column returns the index of the first header matching any of names
+// (case-insensitive), or -1 if none is present
+func (in *CSVInput) Column(names ...string) int {
+	for i, col := range in.Header {
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(col), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sourceLines returns lines first to last (1-based, inclusive) as written
+func sourceLines(lines []string, first, last int) string {
+	if first < 1 || first > len(lines) {
+		return ""
+	}
+	if last < first {
+		last = first
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	return strings.Join(lines[first-1:last], "")
+}
+
+// WriteInputRows writes header and rows to a new CSV file at path. Rows that
+// could not be parsed are written as they appeared in the source file.
+func WriteInputRows(path string, header []string, rows []InputRow) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if row.Fields != nil {
+			if err := writer.Write(row.Fields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		writer.Flush()
+		raw := row.Raw
+		if !strings.HasSuffix(raw, "\n") {
+			raw += "\n"
+		}
+		if _, err := file.WriteString(raw); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
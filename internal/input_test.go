@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCSVInputKeepsMalformedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.csv")
+	content := "pin,name\nP051234567A,Acme\n\"P05\"x,Broken\nP059876543B\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	input, err := ReadCSVInput(path)
+	if err != nil {
+		t.Fatalf("ReadCSVInput returned error: %v", err)
+	}
+
+	if col := input.Column("PIN"); col != 0 {
+		t.Fatalf("Column(PIN) = %d, expected 0", col)
+	}
+	if len(input.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(input.Rows))
+	}
+
+	if row := input.Rows[0]; row.Line != 2 || row.Value(0) != "P051234567A" {
+		t.Fatalf("unexpected first row: %+v", row)
+	}
+	if row := input.Rows[1]; row.Line != 3 || row.Err == nil || row.Raw != "\"P05\"x,Broken\n" {
+		t.Fatalf("expected parse error on line 3, got %+v", row)
+	}
+	if row := input.Rows[2]; row.Line != 4 || row.Value(1) != "" {
+		t.Fatalf("unexpected short row: %+v", row)
+	}
+}
@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Filing statuses recorded in the ledger
+const (
+	FilingAccepted = "accepted"
+	FilingPending  = "pending"
+	FilingRejected = "rejected"
+	FilingUnknown  = "unknown"
+)
+
+// Filing is one NIL return submission, or a later change of its status,
+// recorded in the ledger
+type Filing struct {
+	Key            string          `json:"key"`
+	PIN            string          `json:"pin"`
+	ObligationCode int             `json:"obligation_code"`
+	Period         string          `json:"period"`
+	Status         string          `json:"status"`
+	Reference      string          `json:"reference,omitempty"`
+	Message        string          `json:"message,omitempty"`
+	Profile        string          `json:"profile"`
+	FiledAt        time.Time       `json:"filed_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`                  // when this status was recorded
+	Result         json.RawMessage `json:"result,omitempty" output:"-"` // the API response as returned
+}
+
+// Blocks reports whether the filing prevents filing the same return again.
+// Rejected returns can be corrected and filed again.
+func (f *Filing) Blocks() bool {
+	return f.Status != FilingRejected
+}
+
+// FilingKey identifies a return by PIN, obligation and period. The PIN is
+// normalized so a return is found however its PIN was typed.
+func FilingKey(pin string, obligationCode, year, month int) string {
+	return fmt.Sprintf("%s/%d/%04d%02d", NormalizePIN(pin), obligationCode, year, month)
+}
+
+// Ledger is an append-only JSON Lines log of NIL return filings. Every
+// submission and status change is a new line; the latest line for a key is
+// its current state, and earlier lines are kept as the audit trail. Several
+// processes can share a ledger: lines they append are read before each
+// record and under Lock.
+type Ledger struct {
+	path    string
+	file    *os.File
+	offset  int64      // how much of the file has been read
+	lockMu  sync.Mutex // held with the file lock
+	mu      sync.Mutex
+	latest  map[string]Filing
+	history []Filing
+}
+
+// OpenLedger opens or creates the ledger at path
+func OpenLedger(path string) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open filing ledger: %w", err)
+	}
+
+	ledger := &Ledger{path: path, file: file, latest: make(map[string]Filing)}
+
+	unlock, err := ledger.Lock()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	defer unlock()
+
+	// Lines are only written whole under the lock, so a partial line was left
+	// by a crash; terminate it so new entries start cleanly
+	if info, err := file.Stat(); err == nil && info.Size() > ledger.offset {
+		if _, err := file.WriteString("\n"); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write filing ledger: %w", err)
+		}
+		ledger.offset = info.Size() + 1
+	}
+
+	return ledger, nil
+}
+
+// Path returns the ledger file location
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Lock takes the ledger's file lock, waiting for any other process holding
+// it, and reads the lines appended since the ledger was last read. Holding
+// it from a duplicate check until the filing is recorded keeps two
+// processes from filing the same return.
+func (l *Ledger) Lock() (unlock func(), err error) {
+	l.lockMu.Lock()
+	if err := lockFile(l.file); err != nil {
+		l.lockMu.Unlock()
+		return nil, fmt.Errorf("failed to lock filing ledger: %w", err)
+	}
+	unlock = func() {
+		unlockFile(l.file)
+		l.lockMu.Unlock()
+	}
+
+	l.mu.Lock()
+	err = l.refresh()
+	l.mu.Unlock()
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// Get returns the current state of the filing with key
+func (l *Ledger) Get(key string) (Filing, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	filing, ok := l.latest[key]
+	return filing, ok
+}
+
+// Record appends a filing to the ledger, and reads it back along with any
+// lines other processes appended first
+func (l *Ledger) Record(filing Filing) error {
+	line, err := json.Marshal(filing)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// One append of a whole line, so lines of several writers never mix
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write filing ledger: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write filing ledger: %w", err)
+	}
+	return l.refresh()
+}
+
+// refresh reads the whole lines appended to the file since the last read
+func (l *Ledger) refresh() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read filing ledger: %w", err)
+	}
+	if info.Size() <= l.offset {
+		return nil
+	}
+
+	data := make([]byte, info.Size()-l.offset)
+	n, err := l.file.ReadAt(data, l.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read filing ledger: %w", err)
+	}
+
+	end := bytes.LastIndexByte(data[:n], '\n')
+	if end < 0 {
+		return nil
+	}
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		var filing Filing
+		if err := json.Unmarshal(line, &filing); err != nil {
+			// A truncated line from an interrupted write is ignored
+			continue
+		}
+		l.add(filing)
+	}
+	l.offset += int64(end + 1)
+	return nil
+}
+
+// List returns the current state of every filing, oldest first
+func (l *Ledger) List() []Filing {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	filings := make([]Filing, 0, len(l.latest))
+	for _, filing := range l.latest {
+		filings = append(filings, filing)
+	}
+	sort.SliceStable(filings, func(i, j int) bool {
+		if filings[i].FiledAt.Equal(filings[j].FiledAt) {
+			return filings[i].Key < filings[j].Key
+		}
+		return filings[i].FiledAt.Before(filings[j].FiledAt)
+	})
+	return filings
+}
+
+// History returns every recorded line, in the order written
+func (l *Ledger) History() []Filing {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Filing(nil), l.history...)
+}
+
+// Close closes the ledger file
+func (l *Ledger) Close() error {
+	return l.file.Close()
+}
+
+func (l *Ledger) add(filing Filing) {
+	l.latest[filing.Key] = filing
+	l.history = append(l.history, filing)
+}
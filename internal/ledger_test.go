@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerKeepsLatestAndHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filings", "default.jsonl")
+
+	ledger, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	key := FilingKey("P051234567A", 4, 2024, 1)
+	if key != "P051234567A/4/202401" {
+		t.Fatalf("FilingKey = %q", key)
+	}
+
+	first := Filing{Key: key, Status: FilingRejected, FiledAt: time.Now()}
+	second := Filing{Key: key, Status: FilingAccepted, Reference: "NIL-1", FiledAt: time.Now()}
+	for _, f := range []Filing{first, second} {
+		if err := ledger.Record(f); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+	ledger.Close()
+
+	// Simulate a crash in the middle of writing a line
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	file.WriteString(`{"key":"P0`)
+	file.Close()
+
+	reopened, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("reopening ledger returned error: %v", err)
+	}
+	defer reopened.Close()
+
+	current, ok := reopened.Get(key)
+	if !ok || current.Status != FilingAccepted || !current.Blocks() {
+		t.Fatalf("expected latest accepted filing, got %+v", current)
+	}
+	if len(reopened.History()) != 2 || len(reopened.List()) != 1 {
+		t.Fatalf("expected 2 history lines and 1 filing, got %d and %d", len(reopened.History()), len(reopened.List()))
+	}
+
+	if err := reopened.Record(Filing{Key: "other", FiledAt: time.Now()}); err != nil {
+		t.Fatalf("Record after repair returned error: %v", err)
+	}
+	if again, err := OpenLedger(path); err != nil || len(again.History()) != 3 {
+		t.Fatalf("expected entry written after the partial line to load, got %v", err)
+	} else {
+		again.Close()
+	}
+}
+
+func TestLedgerSharedBetweenProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.jsonl")
+	first, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	defer first.Close()
+	second, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("OpenLedger returned error: %v", err)
+	}
+	defer second.Close()
+
+	key := FilingKey("P051234567A", 4, 2024, 1)
+	if err := second.Record(Filing{Key: key, Status: FilingPending, FiledAt: time.Now()}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if _, ok := first.Get(key); ok {
+		t.Fatal("expected the other ledger's filing to be unseen before locking")
+	}
+
+	unlock, err := first.Lock()
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+	if filing, ok := first.Get(key); !ok || filing.Status != FilingPending {
+		t.Fatalf("expected Lock to read the other ledger's filing, got %+v", filing)
+	}
+
+	// The other ledger waits for the lock
+	locked := make(chan struct{})
+	go func() {
+		unlockSecond, err := second.Lock()
+		if err == nil {
+			unlockSecond()
+		}
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("expected the second Lock to wait for the first")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := first.Record(Filing{Key: key, Status: FilingAccepted, FiledAt: time.Now()}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	unlock()
+	<-locked
+
+	if filing, _ := second.Get(key); filing.Status != FilingAccepted || len(second.History()) != 2 {
+		t.Errorf("expected both lines in order, got %+v and %d lines", filing, len(second.History()))
+	}
+	if len(first.History()) != 2 {
+		t.Errorf("expected 2 lines, got %d", len(first.History()))
+	}
+}
+
+func TestFilingKeyNormalizesPIN(t *testing.T) {
+	want := "P051234567A/4/202401"
+	for _, pin := range []string{"P051234567A", "p051234567a", " P051234567A", "P051234567a\t"} {
+		if got := FilingKey(pin, 4, 2024, 1); got != want {
+			t.Errorf("FilingKey(%q) = %q, expected %q", pin, got, want)
+		}
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	result := struct {
+		Ref    string `json:"referenceNumber"`
+		Status string `json:"status"`
+	}{"NIL-42", "accepted"}
+
+	if got := LookupField(result, "reference_number"); got != "NIL-42" {
+		t.Fatalf("LookupField = %q, expected NIL-42", got)
+	}
+	if got := LookupField(result, "ack_number", "status"); got != "accepted" {
+		t.Fatalf("LookupField fallback = %q", got)
+	}
+	if got := LookupField(nil, "status"); got != "" {
+		t.Fatalf("expected empty value for nil, got %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// readyTimeout bounds the readiness check behind /readyz
+const readyTimeout = 10 * time.Second
+
+// Metrics collects Prometheus metrics about GavaConnect operations. A nil
+// *Metrics is valid and records nothing, so callers need not check whether
+// metrics are enabled.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests      *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	latency       *prometheus.HistogramVec
+	cache         *prometheus.CounterVec
+	tokens        *prometheus.CounterVec
+	batchRecords  *prometheus.CounterVec
+	batchDuration *prometheus.HistogramVec
+}
+
+// NewMetrics creates the metrics, with Go runtime and process metrics, in a
+// registry of their own
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kra_api_requests_total",
+			Help: "GavaConnect API calls by operation.",
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kra_api_errors_total",
+			Help: "Failed GavaConnect API calls by operation and error class (input, auth, network, timeout, canceled, api).",
+		}, []string{"operation", "class"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kra_api_request_duration_seconds",
+			Help:    "Duration of GavaConnect API calls by operation.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"operation"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kra_cache_lookups_total",
+			Help: "Result cache lookups by operation and result (hit or miss).",
+		}, []string{"operation", "result"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kra_token_refreshes_total",
+			Help: "OAuth token requests by result (success or failure).",
+		}, []string{"result"}),
+		batchRecords: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kra_batch_records_total",
+			Help: "Batch records processed by operation and status.",
+		}, []string{"operation", "status"}),
+		batchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kra_batch_duration_seconds",
+			Help:    "Duration of batch runs by operation.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.errors, m.latency, m.cache, m.tokens, m.batchRecords, m.batchDuration,
+	)
+	return m
+}
+
+// ObserveCall records one API call of operation that took d. class is empty
+// for a successful call, or the class of its error.
+func (m *Metrics) ObserveCall(operation string, d time.Duration, class string) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(operation).Inc()
+	m.latency.WithLabelValues(operation).Observe(d.Seconds())
+	if class != "" {
+		m.errors.WithLabelValues(operation, class).Inc()
+	}
+}
+
+// CacheLookup records a result cache lookup for operation
+func (m *Metrics) CacheLookup(operation string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.WithLabelValues(operation, result).Inc()
+}
+
+// TokenFetched records an OAuth token request and its error
+func (m *Metrics) TokenFetched(err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.tokens.WithLabelValues(result).Inc()
+}
+
+// ObserveBatch records a finished batch run of operation with the number of
+// records per status
+func (m *Metrics) ObserveBatch(operation string, d time.Duration, statuses map[string]int) {
+	if m == nil {
+		return
+	}
+	m.batchDuration.WithLabelValues(operation).Observe(d.Seconds())
+	for status, n := range statuses {
+		m.batchRecords.WithLabelValues(operation, status).Add(float64(n))
+	}
+}
+
+// Mount serves the metrics at /metrics, and liveness and readiness probes at
+// /healthz and /readyz on mux. /readyz answers 503 while ready fails.
+func (m *Metrics) Mount(mux *http.ServeMux, ready func(context.Context) error) {
+	mux.Handle("GET /metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+		if err := ready(ctx); err != nil {
+			WriteJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+}
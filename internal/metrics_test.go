@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	m := NewMetrics()
+	m.ObserveCall("verify-pin", 120*time.Millisecond, "")
+	m.ObserveCall("verify-pin", 2*time.Second, "timeout")
+	m.CacheLookup("verify-pin", true)
+	m.TokenFetched(errors.New("invalid_client"))
+	m.ObserveBatch("check-tcc", 3*time.Second, map[string]int{StatusOK: 8, StatusError: 2})
+
+	mux := http.NewServeMux()
+	m.Mount(mux, func(context.Context) error { return nil })
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics returned status %d", rec.Code)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`kra_api_requests_total{operation="verify-pin"} 2`,
+		`kra_api_errors_total{class="timeout",operation="verify-pin"} 1`,
+		`kra_api_request_duration_seconds_count{operation="verify-pin"} 2`,
+		`kra_cache_lookups_total{operation="verify-pin",result="hit"} 1`,
+		`kra_token_refreshes_total{result="failure"} 1`,
+		`kra_batch_records_total{operation="check-tcc",status="ok"} 8`,
+		`kra_batch_duration_seconds_count{operation="check-tcc"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
+
+func TestMetricsNil(t *testing.T) {
+	var m *Metrics
+	m.ObserveCall("verify-pin", time.Second, "api")
+	m.CacheLookup("verify-pin", false)
+	m.TokenFetched(nil)
+	m.ObserveBatch("verify-pin", time.Second, map[string]int{StatusOK: 1})
+}
+
+func TestMetricsProbes(t *testing.T) {
+	var ready error
+	mux := http.NewServeMux()
+	NewMetrics().Mount(mux, func(context.Context) error { return ready })
+
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz returned status %d", code)
+	}
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz returned status %d while ready", code)
+	}
+
+	ready = errors.New("token acquisition failed")
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz returned status %d while not ready", code)
+	}
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz returned status %d while not ready", code)
+	}
+}
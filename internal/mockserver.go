@@ -0,0 +1,318 @@
+package internal
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed mockfixtures/*.yaml
+var defaultMockFixtures embed.FS
+
+// MockResponse is one canned response of a mock route
+type MockResponse struct {
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    interface{}       `yaml:"body"`    // encoded as JSON unless it is a string
+	Latency string            `yaml:"latency"` // e.g. 250ms; overrides the route latency
+}
+
+// MockRoute maps requests to canned responses. A route matches when the
+// method and path are equal and Match, if set, occurs in the query string or
+// request body. With Sequence, the nth matching request gets the nth response
+// and the last one repeats, which scripts retries and transient failures.
+type MockRoute struct {
+	Name         string `yaml:"name"`
+	Method       string `yaml:"method"`
+	Path         string `yaml:"path"`
+	Match        string `yaml:"match"`
+	MockResponse `yaml:",inline"`
+	Sequence     []MockResponse `yaml:"sequence"`
+	Jitter       string         `yaml:"jitter"`       // random extra latency up to this
+	ErrorRate    float64        `yaml:"error_rate"`   // fraction of requests answered with ErrorStatus
+	ErrorStatus  int            `yaml:"error_status"` // default 503
+
+	calls int
+}
+
+// mockFixtureFile is the format of a fixture file
+type mockFixtureFile struct {
+	Routes []MockRoute `yaml:"routes"`
+}
+
+// MockOptions are server-wide latency and error injection settings, used for
+// routes that do not set their own
+type MockOptions struct {
+	Latency     time.Duration
+	Jitter      time.Duration
+	ErrorRate   float64
+	ErrorStatus int
+	Log         io.Writer // request log, or nil
+}
+
+// MockServer serves fixture routes imitating the GavaConnect API
+type MockServer struct {
+	routes []*MockRoute
+	opts   MockOptions
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// LoadMockFixtures reads every .yaml, .yml and .json fixture file in dir, in
+// file name order
+func LoadMockFixtures(dir string) ([]MockRoute, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var routes []MockRoute
+	for _, name := range names {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+
+		var file mockFixtureFile
+		if err := yaml.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+		for i, route := range file.Routes {
+			if err := validateMockRoute(&route); err != nil {
+				return nil, fmt.Errorf("invalid fixture %s, route %d: %w", name, i+1, err)
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no fixture routes found in %s", dir)
+	}
+	return routes, nil
+}
+
+// validateMockRoute checks a route and fills in defaults
+func validateMockRoute(route *MockRoute) error {
+	if route.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	route.Method = strings.ToUpper(route.Method)
+	if route.Name == "" {
+		route.Name = strings.TrimSpace(route.Method + " " + route.Path)
+	}
+
+	for _, d := range []string{route.Latency, route.Jitter} {
+		if _, err := parseOptionalDuration(d); err != nil {
+			return err
+		}
+	}
+	for _, resp := range route.Sequence {
+		if _, err := parseOptionalDuration(resp.Latency); err != nil {
+			return err
+		}
+	}
+	if route.ErrorRate < 0 || route.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// WriteMockFixtures writes the sample fixtures into dir without overwriting
+// existing files and returns the names of the files written
+func WriteMockFixtures(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+
+	var written []string
+	err := fs.WalkDir(defaultMockFixtures, "mockfixtures", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		target := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		raw, err := defaultMockFixtures.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, raw, 0o644); err != nil {
+			return fmt.Errorf("failed to write fixture: %w", err)
+		}
+		written = append(written, entry.Name())
+		return nil
+	})
+	return written, err
+}
+
+// NewMockServer creates a mock server for routes
+func NewMockServer(routes []MockRoute, opts MockOptions) *MockServer {
+	if opts.ErrorStatus == 0 {
+		opts.ErrorStatus = http.StatusServiceUnavailable
+	}
+
+	s := &MockServer{opts: opts, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for i := range routes {
+		route := routes[i]
+		s.routes = append(s.routes, &route)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	body, _ := io.ReadAll(r.Body)
+	route := s.match(r, string(body))
+	if route == nil {
+		s.write(w, http.StatusNotFound, nil, map[string]string{
+			"error": fmt.Sprintf("no fixture for %s %s", r.Method, r.URL.Path),
+		})
+		s.logf("%s %s -> 404 (no fixture)\n", r.Method, r.URL.RequestURI())
+		return
+	}
+
+	resp, delay, failed := s.plan(route)
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	if failed {
+		status := route.ErrorStatus
+		if status == 0 {
+			status = s.opts.ErrorStatus
+		}
+		s.write(w, status, nil, map[string]string{"error": "injected failure"})
+		s.logf("%s %s -> %d (%s, injected) %s\n", r.Method, r.URL.RequestURI(), status, route.Name, time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	s.write(w, status, resp.Headers, resp.Body)
+	s.logf("%s %s -> %d (%s) %s\n", r.Method, r.URL.RequestURI(), status, route.Name, time.Since(start).Round(time.Millisecond))
+}
+
+// match finds the first route for the request
+func (s *MockServer) match(r *http.Request, body string) *MockRoute {
+	for _, route := range s.routes {
+		if route.Method != "" && route.Method != r.Method {
+			continue
+		}
+		if route.Path != r.URL.Path {
+			continue
+		}
+		if route.Match != "" && !strings.Contains(r.URL.RawQuery, route.Match) && !strings.Contains(body, route.Match) {
+			continue
+		}
+		return route
+	}
+	return nil
+}
+
+// plan picks the response, delay and whether to inject a failure for the
+// next request on route
+func (s *MockServer) plan(route *MockRoute) (MockResponse, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := route.MockResponse
+	if len(route.Sequence) > 0 {
+		n := route.calls
+		if n >= len(route.Sequence) {
+			n = len(route.Sequence) - 1
+		}
+		resp = route.Sequence[n]
+	}
+	route.calls++
+
+	delay := s.opts.Latency
+	if d, _ := parseOptionalDuration(route.Latency); d > 0 {
+		delay = d
+	}
+	if d, _ := parseOptionalDuration(resp.Latency); d > 0 {
+		delay = d
+	}
+
+	jitter := s.opts.Jitter
+	if d, _ := parseOptionalDuration(route.Jitter); d > 0 {
+		jitter = d
+	}
+	if jitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(jitter)))
+	}
+
+	rate := s.opts.ErrorRate
+	if route.ErrorRate > 0 {
+		rate = route.ErrorRate
+	}
+	failed := rate > 0 && s.rand.Float64() < rate
+
+	return resp, delay, failed
+}
+
+// write sends body as JSON, or as-is when it is a string
+func (s *MockServer) write(w http.ResponseWriter, status int, headers map[string]string, body interface{}) {
+	var raw []byte
+	if text, ok := body.(string); ok {
+		raw = []byte(text)
+	} else if body != nil {
+		raw, _ = json.Marshal(body)
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	for key, value := range headers {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(status)
+	w.Write(raw)
+}
+
+func (s *MockServer) logf(format string, args ...interface{}) {
+	if s.opts.Log != nil {
+		fmt.Fprintf(s.opts.Log, format, args...)
+	}
+}
+
+// parseOptionalDuration parses a duration, treating "" as zero
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
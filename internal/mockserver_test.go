@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func serveMock(t *testing.T, fixture string, opts MockOptions) *httptest.Server {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "routes.yaml"), []byte(fixture), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	routes, err := LoadMockFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadMockFixtures returned error: %v", err)
+	}
+
+	server := httptest.NewServer(NewMockServer(routes, opts))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func post(t *testing.T, url, body string) (int, string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(raw)
+}
+
+func TestMockServerMatchesRoutesInOrder(t *testing.T) {
+	server := serveMock(t, `
+routes:
+  - method: POST
+    path: /pin
+    match: P000000000X
+    body: {is_valid: false}
+  - method: POST
+    path: /pin
+    body: {is_valid: true}
+`, MockOptions{})
+
+	if status, body := post(t, server.URL+"/pin", `{"pin":"P000000000X"}`); status != 200 || !strings.Contains(body, `"is_valid":false`) {
+		t.Fatalf("matched route gave %d %s", status, body)
+	}
+	if status, body := post(t, server.URL+"/pin", `{"pin":"P051234567A"}`); status != 200 || !strings.Contains(body, `"is_valid":true`) {
+		t.Fatalf("fallback route gave %d %s", status, body)
+	}
+	if status, _ := post(t, server.URL+"/unknown", `{}`); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for unmatched path, got %d", status)
+	}
+}
+
+func TestMockServerSequenceAndErrorInjection(t *testing.T) {
+	server := serveMock(t, `
+routes:
+  - path: /flaky
+    sequence:
+      - status: 503
+      - status: 200
+        body: ok
+  - path: /broken
+    error_rate: 1
+    error_status: 502
+    body: never
+`, MockOptions{})
+
+	for i, expected := range []int{503, 200, 200} {
+		if status, _ := post(t, server.URL+"/flaky", ""); status != expected {
+			t.Fatalf("request %d: expected %d, got %d", i+1, expected, status)
+		}
+	}
+	if status, _ := post(t, server.URL+"/broken", ""); status != 502 {
+		t.Fatalf("expected injected 502, got %d", status)
+	}
+}
+
+func TestWriteMockFixturesLoads(t *testing.T) {
+	dir := t.TempDir()
+	written, err := WriteMockFixtures(dir)
+	if err != nil || len(written) == 0 {
+		t.Fatalf("WriteMockFixtures = %v, %v", written, err)
+	}
+	if _, err := LoadMockFixtures(dir); err != nil {
+		t.Fatalf("sample fixtures do not load: %v", err)
+	}
+
+	again, err := WriteMockFixtures(dir)
+	if err != nil || len(again) != 0 {
+		t.Fatalf("expected existing fixtures to be kept, wrote %v, %v", again, err)
+	}
+}
@@ -0,0 +1,310 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification events
+const (
+	EventBatch       = "batch"        // a batch run found invalid or failed records
+	EventChanges     = "changes"      // records changed since a previous run
+	EventTCCExpiring = "tcc.expiring" // watched TCCs are expiring or expired
+	EventTest        = "test"         // sent by "notify test"
+)
+
+// Headers of webhook notifications
+const (
+	WebhookEventHeader     = "X-KRA-CLI-Event"
+	WebhookTimestampHeader = "X-KRA-CLI-Timestamp"
+	WebhookSignatureHeader = "X-KRA-CLI-Signature"
+)
+
+// notificationTextItems is how many items the text of a notification lists
+const notificationTextItems = 50
+
+// notifyHTTPClient sends webhook notifications. It keeps its own transport,
+// taken before any --record or --replay wrapper is installed, so
+// notifications never end up in cassettes or are answered from them.
+var notifyHTTPClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: http.DefaultTransport.(*http.Transport).Clone(),
+}
+
+// Notification is a message about monitoring results
+type Notification struct {
+	Event   string             `json:"event"`
+	Command string             `json:"command"`
+	Profile string             `json:"profile"`
+	Summary string             `json:"summary"`
+	Time    time.Time          `json:"time"`
+	Items   []NotificationItem `json:"items"`
+}
+
+// NotificationItem is one record a notification is about
+type NotificationItem struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ChangeItems describes record changes as notification items, e.g.
+// "valid changed: valid → invalid"
+func ChangeItems(changes []RecordChange) []NotificationItem {
+	items := make([]NotificationItem, len(changes))
+	for i, c := range changes {
+		status := c.Change
+		if c.Field != "" {
+			status = c.Field + " " + c.Change
+		}
+		detail := c.New
+		switch {
+		case c.Old != "" && c.New != "":
+			detail = c.Old + " → " + c.New
+		case c.Old != "":
+			detail = c.Old
+		}
+		items[i] = NotificationItem{Key: c.Key, Status: status, Detail: detail}
+	}
+	return items
+}
+
+// Subject returns a one-line title for the notification
+func (n *Notification) Subject() string {
+	return "[kra-cli] " + n.title()
+}
+
+// title is the command and summary, e.g. "verify-pin: 2 of 40 PINs invalid"
+func (n *Notification) title() string {
+	if n.Command == "" {
+		return n.Summary
+	}
+	return n.Command + ": " + n.Summary
+}
+
+// Text renders the notification as plain text, listing at most
+// notificationTextItems items
+func (n *Notification) Text() string {
+	var b strings.Builder
+	b.WriteString(n.title() + "\n")
+	for i, item := range n.Items {
+		if i == notificationTextItems {
+			fmt.Fprintf(&b, "… and %d more\n", len(n.Items)-i)
+			break
+		}
+		b.WriteString("- " + item.Key + ": " + item.Status)
+		if item.Detail != "" {
+			b.WriteString(" (" + item.Detail + ")")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\nProfile %s, %s\n", n.Profile, n.Time.Local().Format("2006-01-02 15:04:05"))
+	return b.String()
+}
+
+// NotifyTarget delivers notifications to one destination
+type NotifyTarget interface {
+	Notify(ctx context.Context, n *Notification) error
+	// Destination describes where notifications go, without secrets
+	Destination() string
+}
+
+// WebhookTarget posts notifications as JSON. With a secret, each request is
+// signed: X-KRA-CLI-Signature is "sha256=" and the hex HMAC-SHA256 of the
+// X-KRA-CLI-Timestamp value, a dot and the body.
+type WebhookTarget struct {
+	URL    string
+	Secret string
+}
+
+// Notify implements NotifyTarget
+func (t *WebhookTarget) Notify(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{}
+	headers.Set(WebhookEventHeader, n.Event)
+	if t.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers.Set(WebhookTimestampHeader, timestamp)
+		headers.Set(WebhookSignatureHeader, SignWebhook(t.Secret, timestamp, body))
+	}
+	return postJSON(ctx, t.URL, body, headers)
+}
+
+// Destination implements NotifyTarget
+func (t *WebhookTarget) Destination() string {
+	return webhookHost(t.URL)
+}
+
+// SignWebhook returns the X-KRA-CLI-Signature value for a webhook body
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SlackTarget posts notifications to a Slack-compatible incoming webhook
+type SlackTarget struct {
+	URL string
+}
+
+// Notify implements NotifyTarget
+func (t *SlackTarget) Notify(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(map[string]string{"text": n.Text()})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, t.URL, body, nil)
+}
+
+// Destination implements NotifyTarget
+func (t *SlackTarget) Destination() string {
+	return webhookHost(t.URL)
+}
+
+// SMTPTarget emails notifications. Port 465 uses implicit TLS; other ports
+// upgrade with STARTTLS when the server offers it.
+type SMTPTarget struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify implements NotifyTarget
+func (t *SMTPTarget) Notify(ctx context.Context, n *Notification) error {
+	if len(t.To) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if t.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: t.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+
+	client, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && t.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: t.Host}); err != nil {
+			return err
+		}
+	}
+	if t.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(t.From); err != nil {
+		return err
+	}
+	for _, to := range t.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(t.message(n)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Destination implements NotifyTarget
+func (t *SMTPTarget) Destination() string {
+	return strings.Join(t.To, ", ") + " via " + net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// message builds the email for a notification
+func (t *SMTPTarget) message(n *Notification) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", t.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(t.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", n.Subject())
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(n.Text(), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// postJSON posts body to url and fails on a non-2xx response
+func postJSON(ctx context.Context, url string, body []byte, headers http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kra-cli")
+
+	resp, err := notifyHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s answered %s: %s", webhookHost(url), resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// webhookHost keeps the scheme and host of a webhook URL; the path of Slack
+// and similar webhooks is itself a secret
+func webhookHost(raw string) string {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return Redacted
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host + "/…"
+}
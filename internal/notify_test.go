@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotification() *Notification {
+	return &Notification{
+		Event:   EventBatch,
+		Command: "verify-pin",
+		Profile: "default",
+		Summary: "1 of 2 records invalid or failed",
+		Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Items:   []NotificationItem{{Key: "P051234567A", Status: StatusInvalid}},
+	}
+}
+
+func TestWebhookTargetSignsRequests(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer server.Close()
+
+	target := &WebhookTarget{URL: server.URL, Secret: "s3cr3t"}
+	if err := target.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+
+	if got := header.Get(WebhookEventHeader); got != EventBatch {
+		t.Errorf("%s = %q, expected %q", WebhookEventHeader, got, EventBatch)
+	}
+	timestamp := header.Get(WebhookTimestampHeader)
+	if want := SignWebhook("s3cr3t", timestamp, body); header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("%s = %q, expected %q", WebhookSignatureHeader, header.Get(WebhookSignatureHeader), want)
+	}
+
+	var got Notification
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("webhook body is not JSON: %v", err)
+	}
+	if got.Command != "verify-pin" || len(got.Items) != 1 || got.Items[0].Key != "P051234567A" {
+		t.Errorf("unexpected webhook payload: %+v", got)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	// printf '1700000000.{}' | openssl dgst -sha256 -hmac key
+	want := "sha256=9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae"
+	if got := SignWebhook("key", "1700000000", []byte("{}")); got != want {
+		t.Errorf("SignWebhook = %q, expected %q", got, want)
+	}
+}
+
+func TestSlackTargetFailsOnErrorStatus(t *testing.T) {
+	var payload map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	target := &SlackTarget{URL: server.URL + "/services/T000/B000/XXXX"}
+	if err := target.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if !strings.HasPrefix(payload["text"], "verify-pin: 1 of 2 records invalid or failed\n- P051234567A: invalid\n") {
+		t.Errorf("unexpected Slack text: %q", payload["text"])
+	}
+
+	status = http.StatusNotFound
+	err := target.Notify(context.Background(), testNotification())
+	if err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+	if strings.Contains(err.Error(), "XXXX") {
+		t.Errorf("error leaks the webhook path: %v", err)
+	}
+}
+
+func TestNotificationTextLimitsItems(t *testing.T) {
+	n := testNotification()
+	n.Items = make([]NotificationItem, notificationTextItems+5)
+	if text := n.Text(); !strings.Contains(text, "… and 5 more\n") {
+		t.Errorf("expected the text to end the list with a count, got %q", text)
+	}
+}
+
+func TestChangeItems(t *testing.T) {
+	items := ChangeItems([]RecordChange{
+		{Key: "P051234567A", Change: ChangeChanged, Field: "valid", Old: "valid", New: "invalid"},
+		{Key: "P053333333E", Change: ChangeAdded, New: "invalid"},
+	})
+	want := []NotificationItem{
+		{Key: "P051234567A", Status: "valid changed", Detail: "valid → invalid"},
+		{Key: "P053333333E", Status: ChangeAdded, Detail: "invalid"},
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("item %d = %+v, expected %+v", i, items[i], want[i])
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strings"
@@ -14,7 +15,8 @@ import (
 
 // OutputFormatter handles output formatting for different formats (table, JSON, CSV)
 type OutputFormatter struct {
-	Format string // "table", "json", or "csv"
+	Format string    // "table", "json", or "csv"
+	Writer io.Writer // destination; os.Stdout when nil
 }
 
 // NewOutputFormatter creates a new output formatter
@@ -22,6 +24,14 @@ func NewOutputFormatter(format string) *OutputFormatter {
 	return &OutputFormatter{Format: format}
 }
 
+// out returns the destination writer
+func (f *OutputFormatter) out() io.Writer {
+	if f.Writer != nil {
+		return f.Writer
+	}
+	return os.Stdout
+}
+
 // Print outputs data in the specified format
 func (f *OutputFormatter) Print(data interface{}) error {
 	switch strings.ToLower(f.Format) {
@@ -38,14 +48,14 @@ func (f *OutputFormatter) Print(data interface{}) error {
 
 // printJSON outputs data as JSON
 func (f *OutputFormatter) printJSON(data interface{}) error {
-	encoder := json.NewEncoder(os.Stdout)
+	encoder := json.NewEncoder(f.out())
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(data)
 }
 
 // printCSV outputs data as CSV
 func (f *OutputFormatter) printCSV(data interface{}) error {
-	writer := csv.NewWriter(os.Stdout)
+	writer := csv.NewWriter(f.out())
 	defer writer.Flush()
 
 	// Handle slice of structs or maps
@@ -71,7 +81,7 @@ func (f *OutputFormatter) printCSV(data interface{}) error {
 
 // printStructSliceCSV prints a slice of structs as CSV
 func (f *OutputFormatter) printStructSliceCSV(data interface{}) error {
-	writer := csv.NewWriter(os.Stdout)
+	writer := csv.NewWriter(f.out())
 	defer writer.Flush()
 
 	v := reflect.ValueOf(data)
@@ -80,21 +90,17 @@ func (f *OutputFormatter) printStructSliceCSV(data interface{}) error {
 	}
 
 	first := v.Index(0)
+	if first.Kind() == reflect.Interface {
+		first = first.Elem()
+	}
 	if first.Kind() == reflect.Ptr {
 		first = first.Elem()
 	}
 
-	t := first.Type()
-	headers := make([]string, t.NumField())
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		// Use JSON tag if available, otherwise use field name
-		tag := field.Tag.Get("json")
-		if tag != "" && tag != "-" {
-			headers[i] = strings.Split(tag, ",")[0]
-		} else {
-			headers[i] = field.Name
-		}
+	columns := structColumns(first.Type())
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.name
 	}
 
 	if err := writer.Write(headers); err != nil {
@@ -103,14 +109,16 @@ func (f *OutputFormatter) printStructSliceCSV(data interface{}) error {
 
 	for i := 0; i < v.Len(); i++ {
 		item := v.Index(i)
+		if item.Kind() == reflect.Interface {
+			item = item.Elem()
+		}
 		if item.Kind() == reflect.Ptr {
 			item = item.Elem()
 		}
 
-		row := make([]string, t.NumField())
-		for j := 0; j < t.NumField(); j++ {
-			field := item.Field(j)
-			row[j] = fmt.Sprintf("%v", field.Interface())
+		row := make([]string, len(columns))
+		for j, col := range columns {
+			row[j] = formatColumn(item, col)
 		}
 
 		if err := writer.Write(row); err != nil {
@@ -123,7 +131,7 @@ func (f *OutputFormatter) printStructSliceCSV(data interface{}) error {
 
 // printMapSliceCSV prints a slice of maps as CSV
 func (f *OutputFormatter) printMapSliceCSV(data interface{}) error {
-	writer := csv.NewWriter(os.Stdout)
+	writer := csv.NewWriter(f.out())
 	defer writer.Flush()
 
 	v := reflect.ValueOf(data)
@@ -160,7 +168,7 @@ func (f *OutputFormatter) printMapSliceCSV(data interface{}) error {
 
 // printTable outputs data as a formatted table
 func (f *OutputFormatter) printTable(data interface{}) error {
-	table := tablewriter.NewWriter(os.Stdout)
+	table := tablewriter.NewWriter(f.out())
 	table.SetAutoWrapText(false)
 	table.SetAutoFormatHeaders(true)
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
@@ -178,7 +186,7 @@ func (f *OutputFormatter) printTable(data interface{}) error {
 	// Handle slice
 	if v.Kind() == reflect.Slice {
 		if v.Len() == 0 {
-			fmt.Println("No data to display")
+			fmt.Fprintln(f.out(), "No data to display")
 			return nil
 		}
 
@@ -204,24 +212,15 @@ func (f *OutputFormatter) printSingleStructTable(table *tablewriter.Table, data
 		v = v.Elem()
 	}
 
-	t := v.Type()
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		value := v.Field(i)
-
-		// Use JSON tag if available
-		name := field.Name
-		tag := field.Tag.Get("json")
-		if tag != "" && tag != "-" {
-			name = strings.Split(tag, ",")[0]
-		}
+	for _, col := range structColumns(v.Type()) {
+		value, ok := columnValue(v, col)
 
 		// Skip nil pointers
-		if value.Kind() == reflect.Ptr && value.IsNil() {
+		if !ok || (value.Kind() == reflect.Ptr && value.IsNil()) {
 			continue
 		}
 
-		table.Append([]string{name, fmt.Sprintf("%v", value.Interface())})
+		table.Append([]string{col.name, fmt.Sprintf("%v", value.Interface())})
 	}
 
 	table.Render()
@@ -243,7 +242,7 @@ func (f *OutputFormatter) printSingleMapTable(table *tablewriter.Table, data int
 func (f *OutputFormatter) printStructSliceTable(table *tablewriter.Table, data interface{}) error {
 	v := reflect.ValueOf(data)
 	if v.Len() == 0 {
-		fmt.Println("No data to display")
+		fmt.Fprintln(f.out(), "No data to display")
 		return nil
 	}
 
@@ -252,20 +251,10 @@ func (f *OutputFormatter) printStructSliceTable(table *tablewriter.Table, data i
 		first = first.Elem()
 	}
 
-	t := first.Type()
-	headers := make([]string, 0)
-	visibleFields := make([]int, 0)
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("json")
-		if tag != "" && tag != "-" {
-			headers = append(headers, strings.Split(tag, ",")[0])
-			visibleFields = append(visibleFields, i)
-		} else {
-			headers = append(headers, field.Name)
-			visibleFields = append(visibleFields, i)
-		}
+	columns := structColumns(first.Type())
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.name
 	}
 
 	table.SetHeader(headers)
@@ -276,10 +265,9 @@ func (f *OutputFormatter) printStructSliceTable(table *tablewriter.Table, data i
 			item = item.Elem()
 		}
 
-		row := make([]string, len(visibleFields))
-		for j, fieldIdx := range visibleFields {
-			field := item.Field(fieldIdx)
-			row[j] = fmt.Sprintf("%v", field.Interface())
+		row := make([]string, len(columns))
+		for j, col := range columns {
+			row[j] = formatColumn(item, col)
 		}
 
 		table.Append(row)
@@ -293,7 +281,7 @@ func (f *OutputFormatter) printStructSliceTable(table *tablewriter.Table, data i
 func (f *OutputFormatter) printMapSliceTable(table *tablewriter.Table, data interface{}) error {
 	v := reflect.ValueOf(data)
 	if v.Len() == 0 {
-		fmt.Println("No data to display")
+		fmt.Fprintln(f.out(), "No data to display")
 		return nil
 	}
 
@@ -321,6 +309,100 @@ func (f *OutputFormatter) printMapSliceTable(table *tablewriter.Table, data inte
 	return nil
 }
 
+// column is a printable struct field, possibly nested inside an inline field
+type column struct {
+	name  string
+	index []int
+}
+
+// structColumns lists the printable columns of a struct type, named after
+// their JSON tags. Fields excluded from JSON with `json:"-"` or from tables
+// with `output:"-"` are skipped, and
+// fields tagged `output:"inline"` are expanded into their own
+// columns; inner names that clash with outer ones are prefixed with the
+// inline field's name.
+func structColumns(t reflect.Type) []column {
+	type inlineColumn struct {
+		column
+		prefix string
+	}
+
+	columns := make([]column, 0, t.NumField())
+	inline := make([]inlineColumn, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("json") == "-" || field.Tag.Get("output") == "-" {
+			continue
+		}
+		name := fieldName(field)
+
+		inner := field.Type
+		if inner.Kind() == reflect.Ptr {
+			inner = inner.Elem()
+		}
+
+		if field.Tag.Get("output") == "inline" && inner.Kind() == reflect.Struct {
+			for _, col := range structColumns(inner) {
+				col.index = append([]int{i}, col.index...)
+				inline = append(inline, inlineColumn{column: col, prefix: name + "_"})
+			}
+			continue
+		}
+
+		columns = append(columns, column{name: name, index: []int{i}})
+	}
+
+	taken := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		taken[col.name] = true
+	}
+	for _, col := range inline {
+		if taken[col.name] {
+			col.name = col.prefix + col.name
+		}
+		taken[col.name] = true
+		columns = append(columns, col.column)
+	}
+
+	return columns
+}
+
+// fieldName returns the JSON tag name of a struct field, or its Go name
+func fieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag != "" && tag != "-" {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// columnValue walks col's index path from v, reporting false if it passes
+// through a nil pointer
+func columnValue(v reflect.Value, col column) (reflect.Value, bool) {
+	for i, idx := range col.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// formatColumn formats the value of col in v, using "" for missing values
+func formatColumn(v reflect.Value, col column) string {
+	value, ok := columnValue(v, col)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
+
 // PrintError prints an error message to stderr
 func PrintError(err error) {
 	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
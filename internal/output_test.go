@@ -53,3 +53,46 @@ func TestOutputFormatterUnsupportedFormat(t *testing.T) {
 		t.Fatalf("expected error for unsupported format")
 	}
 }
+
+func TestOutputFormatterCSVInlinesBatchResult(t *testing.T) {
+	type result struct {
+		PIN    string `json:"pin"`
+		Status string `json:"status"`
+	}
+
+	formatter := NewOutputFormatter("csv")
+	records := []BatchRecord[*result]{
+		{Line: 2, Input: "P051234567A", Status: StatusOK, Result: &result{PIN: "P051234567A", Status: "active"}},
+		{Line: 3, Input: "BAD", Status: StatusError, Error: "missing PIN"},
+	}
+
+	out := captureStdout(t, func() {
+		if err := formatter.Print(records); err != nil {
+			t.Fatalf("Print returned error: %v", err)
+		}
+	})
+
+	want := "line,input,status,error,pin,result_status\n" +
+		"2,P051234567A,ok,,P051234567A,active\n" +
+		"3,BAD,error,missing PIN,,\n"
+	if out != want {
+		t.Fatalf("unexpected CSV output:\n%s\nexpected:\n%s", out, want)
+	}
+}
+
+func TestOutputFormatterCSVSingleStruct(t *testing.T) {
+	type result struct {
+		PIN   string `json:"pin"`
+		Valid bool   `json:"is_valid"`
+	}
+
+	out := captureStdout(t, func() {
+		if err := NewOutputFormatter("csv").Print(&result{PIN: "P051234567A", Valid: true}); err != nil {
+			t.Fatalf("Print returned error: %v", err)
+		}
+	})
+
+	if want := "pin,is_valid\nP051234567A,true\n"; out != want {
+		t.Fatalf("unexpected CSV output %q, expected %q", out, want)
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Taxpayer types encoded in the first letter of a KRA PIN
+const (
+	PINTypeIndividual    = "individual"     // A-prefixed PINs
+	PINTypeNonIndividual = "non-individual" // P-prefixed PINs: companies, partnerships, trusts
+)
+
+// PINCheck is the result of checking a PIN's structure offline
+type PINCheck struct {
+	PIN    string `json:"pin"`
+	Valid  bool   `json:"valid"`
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// NormalizePIN returns pin as KRA writes it: upper case, without surrounding
+// spaces
+func NormalizePIN(pin string) string {
+	return strings.ToUpper(strings.TrimSpace(pin))
+}
+
+// LintPIN checks that pin has the structure of a KRA PIN: a letter A
+// (individual) or P (non-individual), nine digits and a trailing letter, e.g.
+// P051234567A. KRA does not publish a check-digit algorithm, so a well-formed
+// PIN may still be unregistered; only the API can confirm that.
+func LintPIN(pin string) PINCheck {
+	check := PINCheck{PIN: pin}
+	normalized := NormalizePIN(pin)
+
+	switch {
+	case normalized == "":
+		check.Reason = "empty PIN"
+	case len(normalized) != 11:
+		check.Reason = fmt.Sprintf("must be 11 characters, got %d", len(normalized))
+	case normalized[0] != 'A' && normalized[0] != 'P':
+		check.Reason = fmt.Sprintf("must start with A (individual) or P (non-individual), got %q", normalized[:1])
+	case !isDigits(normalized[1:10]):
+		check.Reason = "characters 2-10 must be digits"
+	case normalized[10] < 'A' || normalized[10] > 'Z':
+		check.Reason = fmt.Sprintf("must end with a letter, got %q", normalized[10:])
+	default:
+		check.Valid = true
+	}
+
+	if check.Valid {
+		check.Type = PINTypeNonIndividual
+		if normalized[0] == 'A' {
+			check.Type = PINTypeIndividual
+		}
+	}
+	return check
+}
+
+// isDigits reports whether s consists only of ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
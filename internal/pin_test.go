@@ -0,0 +1,31 @@
+package internal
+
+import "testing"
+
+func TestLintPIN(t *testing.T) {
+	cases := []struct {
+		pin   string
+		valid bool
+		kind  string
+	}{
+		{"P051234567A", true, PINTypeNonIndividual},
+		{"A001234567Z", true, PINTypeIndividual},
+		{" a001234567z ", true, PINTypeIndividual},
+		{"", false, ""},
+		{"INVALID", false, ""},
+		{"X051234567A", false, ""},
+		{"P05123456AA", false, ""},
+		{"P0512345678", false, ""},
+		{"P051234567AB", false, ""},
+	}
+
+	for _, c := range cases {
+		got := LintPIN(c.pin)
+		if got.Valid != c.valid || got.Type != c.kind {
+			t.Fatalf("LintPIN(%q) = %+v, expected valid=%v type=%q", c.pin, got, c.valid, c.kind)
+		}
+		if !got.Valid && got.Reason == "" {
+			t.Fatalf("LintPIN(%q) gave no reason", c.pin)
+		}
+	}
+}
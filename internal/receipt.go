@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReceiptAlgorithm is the signature algorithm of signed receipts
+const ReceiptAlgorithm = "ed25519"
+
+// Receipt is the acknowledgment of a filed return kept as proof of filing
+type Receipt struct {
+	PIN            string    `json:"pin"`
+	ObligationCode int       `json:"obligation_code"`
+	Period         string    `json:"period"`
+	SubmittedAt    time.Time `json:"submitted_at"`
+	Reference      string    `json:"reference,omitempty"`
+	Status         string    `json:"status"`
+	Message        string    `json:"message,omitempty"`
+	Profile        string    `json:"profile"`
+}
+
+// ReceiptSignature signs the exact bytes of a receipt document
+type ReceiptSignature struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // base64
+	Value     string `json:"value"`      // base64
+}
+
+// SignedReceipt is a receipt as written to disk. The signature covers the
+// compact JSON encoding of Receipt, so re-indenting the file keeps it valid.
+type SignedReceipt struct {
+	Receipt   json.RawMessage  `json:"receipt"`
+	Signature ReceiptSignature `json:"signature"`
+}
+
+// ReceiptFromFiling builds the receipt of a ledger filing
+func ReceiptFromFiling(f Filing) Receipt {
+	return Receipt{
+		PIN:            f.PIN,
+		ObligationCode: f.ObligationCode,
+		Period:         f.Period,
+		SubmittedAt:    f.FiledAt,
+		Reference:      f.Reference,
+		Status:         f.Status,
+		Message:        f.Message,
+		Profile:        f.Profile,
+	}
+}
+
+// ReceiptName is the file name, without extension, of the receipt for a
+// PIN, obligation and period
+func ReceiptName(pin string, obligationCode int, period string) string {
+	return fmt.Sprintf("%s_%d_%s", strings.ToUpper(pin), obligationCode, period)
+}
+
+// SignReceipt signs r with key
+func SignReceipt(r Receipt, key ed25519.PrivateKey) (*SignedReceipt, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedReceipt{
+		Receipt: payload,
+		Signature: ReceiptSignature{
+			Algorithm: ReceiptAlgorithm,
+			PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+		},
+	}, nil
+}
+
+// Verify checks the signature against the public key in the receipt and
+// returns the receipt. Checking that the key is the expected one is up to
+// the caller.
+func (s *SignedReceipt) Verify() (Receipt, error) {
+	var r Receipt
+	if s.Signature.Algorithm != ReceiptAlgorithm {
+		return r, fmt.Errorf("unsupported signature algorithm %q", s.Signature.Algorithm)
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(s.Signature.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return r, errors.New("invalid public key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(s.Signature.Value)
+	if err != nil {
+		return r, errors.New("invalid signature encoding")
+	}
+
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, s.Receipt); err != nil {
+		return r, fmt.Errorf("invalid receipt: %w", err)
+	}
+	if !ed25519.Verify(publicKey, payload.Bytes(), signature) {
+		return r, errors.New("signature does not match the receipt")
+	}
+	if err := json.Unmarshal(s.Receipt, &r); err != nil {
+		return r, fmt.Errorf("invalid receipt: %w", err)
+	}
+	return r, nil
+}
+
+// LoadSignedReceipt reads a signed receipt file
+func LoadSignedReceipt(path string) (*SignedReceipt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt: %w", err)
+	}
+
+	var s SignedReceipt
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid receipt file %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// WriteReceipts writes the signed JSON and the PDF receipt of r to dir and
+// returns their paths
+func WriteReceipts(dir string, r Receipt, key ed25519.PrivateKey) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create receipt directory: %w", err)
+	}
+
+	signed, err := SignReceipt(r, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(dir, ReceiptName(r.PIN, r.ObligationCode, r.Period))
+	jsonPath, pdfPath := base+".json", base+".pdf"
+
+	if err := writeFileAtomic(jsonPath, append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write receipt: %w", err)
+	}
+	if err := writeFileAtomic(pdfPath, ReceiptPDF(r, signed.Signature)); err != nil {
+		return nil, fmt.Errorf("failed to write receipt: %w", err)
+	}
+	return []string{jsonPath, pdfPath}, nil
+}
+
+// ReceiptPDF renders r as a single-page PDF document
+func ReceiptPDF(r Receipt, sig ReceiptSignature) []byte {
+	status := strings.ToUpper(r.Status)
+	lines := []pdfLine{
+		{18, "NIL Return Acknowledgment Receipt"},
+		{10, ""},
+		{12, "KRA PIN:            " + r.PIN},
+		{12, fmt.Sprintf("Obligation code:    %d", r.ObligationCode)},
+		{12, "Tax period:         " + formatReceiptPeriod(r.Period)},
+		{12, "Submitted:          " + r.SubmittedAt.UTC().Format("2006-01-02 15:04:05 UTC")},
+		{12, "KRA reference:      " + valueOr(r.Reference, "-")},
+		{12, "Status:             " + status},
+	}
+	if r.Message != "" {
+		lines = append(lines, pdfLine{12, "Message:            " + r.Message})
+	}
+	lines = append(lines,
+		pdfLine{10, ""},
+		pdfLine{9, "Signed (" + sig.Algorithm + ") by key " + sig.PublicKey},
+		pdfLine{9, "Signature: " + sig.Value[:min(len(sig.Value), 44)]},
+		pdfLine{9, "           " + sig.Value[min(len(sig.Value), 44):]},
+		pdfLine{9, "The signed JSON receipt with the same name is the verifiable record."},
+	)
+	return renderPDF(lines)
+}
+
+type pdfLine struct {
+	size int
+	text string
+}
+
+// renderPDF writes a minimal A4 PDF with one line of monospaced text per
+// entry, which is all a receipt needs and avoids a PDF library dependency
+func renderPDF(lines []pdfLine) []byte {
+	var content bytes.Buffer
+	content.WriteString("BT\n")
+	y := 780
+	for _, line := range lines {
+		font := "/F1"
+		if line.size >= 14 {
+			font = "/F2"
+		}
+		fmt.Fprintf(&content, "%s %d Tf 1 0 0 1 56 %d Tm (%s) Tj\n", font, line.size, y, pdfEscape(line.text))
+		y -= line.size + 8
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
+
+// pdfEscape escapes a PDF string literal, replacing characters the standard
+// fonts cannot show
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatReceiptPeriod shows a YYYYMM period as YYYY-MM
+func formatReceiptPeriod(period string) string {
+	if len(period) == 6 {
+		return period[:4] + "-" + period[4:]
+	}
+	return period
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// writeFileAtomic replaces path with data so a receipt is never left half written
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWriteReceipts(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+
+	receipt := Receipt{
+		PIN:            "P051234567A",
+		ObligationCode: 4,
+		Period:         "202401",
+		SubmittedAt:    time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC),
+		Reference:      "NIL-2024-000001",
+		Status:         FilingAccepted,
+		Profile:        "default",
+	}
+
+	dir := t.TempDir()
+	paths, err := WriteReceipts(dir, receipt, key)
+	if err != nil {
+		t.Fatalf("WriteReceipts returned error: %v", err)
+	}
+	if want := filepath.Join(dir, "P051234567A_4_202401.json"); paths[0] != want {
+		t.Errorf("expected JSON receipt at %s, got %s", want, paths[0])
+	}
+
+	signed, err := LoadSignedReceipt(paths[0])
+	if err != nil {
+		t.Fatalf("LoadSignedReceipt returned error: %v", err)
+	}
+	got, err := signed.Verify()
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if got != receipt {
+		t.Errorf("expected %+v, got %+v", receipt, got)
+	}
+
+	// Any change to the signed receipt breaks the signature
+	signed.Receipt = bytes.Replace(signed.Receipt, []byte("accepted"), []byte("rejected"), 1)
+	if _, err := signed.Verify(); err == nil {
+		t.Error("expected a tampered receipt to fail verification")
+	}
+
+	pdf, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatalf("failed to read PDF receipt: %v", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.Contains(pdf, []byte("(KRA reference:      NIL-2024-000001)")) {
+		t.Fatalf("unexpected PDF receipt:\n%s", pdf)
+	}
+
+	// Every cross-reference entry must point at its object
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf, -1)
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 xref entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if !bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Errorf("xref entry %d does not point at its object", i+1)
+		}
+	}
+}
+
+func TestSignedReceiptRoundTrip(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	signed, err := SignReceipt(Receipt{PIN: "A012345678B", Status: FilingPending}, key)
+	if err != nil {
+		t.Fatalf("SignReceipt returned error: %v", err)
+	}
+
+	// Re-indenting the file keeps the signed bytes intact
+	data, _ := json.MarshalIndent(signed, "", "\t")
+	var loaded SignedReceipt
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("failed to decode receipt: %v", err)
+	}
+	if _, err := loaded.Verify(); err != nil {
+		t.Errorf("Verify returned error after re-indenting: %v", err)
+	}
+}
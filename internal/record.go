@@ -0,0 +1,20 @@
+package internal
+
+// Batch record statuses
+const (
+	StatusOK      = "ok"      // the API call succeeded and the item is valid
+	StatusInvalid = "invalid" // the API call succeeded but the item is not valid
+	StatusError   = "error"   // the row could not be parsed or the API call failed
+	StatusSkipped = "skipped" // the row was deliberately not sent, e.g. a duplicate
+)
+
+// BatchRecord is the outcome of processing one input row in batch mode.
+// Every input row produces a record, including rows that failed.
+type BatchRecord[T any] struct {
+	Line   int    `json:"line"`
+	Input  string `json:"input"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Result T      `json:"result,omitempty" output:"inline"`
+	Err    error  `json:"-"` // underlying error for StatusError records
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// hopHeaders apply to a single connection and are not forwarded by a Relay
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection", "TE", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Relay is a loopback HTTP server that forwards every request it receives to
+// the scheme and host of its target URL through a transport. The SDK has no
+// option for its HTTP client, so the SDK is given relay URLs instead of the
+// GavaConnect ones: whatever HTTP client it uses, every request it sends then
+// passes through the transport.
+type Relay struct {
+	target    *url.URL
+	transport http.RoundTripper
+	listener  net.Listener
+	server    *http.Server
+}
+
+// StartRelay starts a relay to target on a free loopback port
+func StartRelay(target string, transport http.RoundTripper) (*Relay, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", target)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start relay: %w", err)
+	}
+
+	r := &Relay{target: u, transport: transport, listener: listener}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+	return r, nil
+}
+
+// URL returns the target URL with the relay's address in place of its scheme
+// and host
+func (r *Relay) URL() string {
+	u := *r.target
+	u.Scheme = "http"
+	u.Host = r.listener.Addr().String()
+	return u.String()
+}
+
+// Close stops the relay
+func (r *Relay) Close() error {
+	return r.server.Close()
+}
+
+// ServeHTTP implements http.Handler
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	out := req.Clone(req.Context())
+	out.RequestURI = ""
+	out.URL.Scheme = r.target.Scheme
+	out.URL.Host = r.target.Host
+	out.Host = ""
+	for _, name := range hopHeaders {
+		out.Header.Del(name)
+	}
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		relayError(w, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, name := range hopHeaders {
+		resp.Header.Del(name)
+	}
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// relayError answers a request that could not be forwarded, with the error as
+// the body: refused client credentials with the token endpoint's 401 or 403,
+// a timeout with 504 and anything else with 502
+func relayError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	var authErr *TokenAuthError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &authErr):
+		status = authErr.StatusCode
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		status = http.StatusGatewayTimeout
+	}
+	http.Error(w, "relay: "+err.Error(), status)
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRelay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Seen", r.Method+" "+r.URL.RequestURI()+" "+string(body)+" "+r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	relay, err := StartRelay(upstream.URL+"/v1/token/generate?grant_type=client_credentials", http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("StartRelay returned error: %v", err)
+	}
+	defer relay.Close()
+
+	if !strings.HasPrefix(relay.URL(), "http://127.0.0.1:") || !strings.HasSuffix(relay.URL(), "/v1/token/generate?grant_type=client_credentials") {
+		t.Fatalf("unexpected relay URL %s", relay.URL())
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, relay.URL(), strings.NewReader("payload"))
+	req.Header.Set("Authorization", "Bearer abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request through the relay failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	want := "POST /v1/token/generate?grant_type=client_credentials payload Bearer abc"
+	if resp.StatusCode != http.StatusCreated || string(body) != "ok" || resp.Header.Get("X-Seen") != want {
+		t.Errorf("relayed response %d %q seen as %q, expected 201 \"ok\" seen as %q", resp.StatusCode, body, resp.Header.Get("X-Seen"), want)
+	}
+}
+
+func TestRelayErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("dial tcp: connection refused"), http.StatusBadGateway},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{&TokenAuthError{StatusCode: http.StatusUnauthorized}, http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		relay, err := StartRelay("https://sbx.kra.go.ke", roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, c.err }))
+		if err != nil {
+			t.Fatalf("StartRelay returned error: %v", err)
+		}
+
+		resp, err := http.Get(relay.URL() + "/checker/v1/pinbypin")
+		relay.Close()
+		if err != nil {
+			t.Fatalf("request through the relay failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("relay answered %d for %v, expected %d", resp.StatusCode, c.err, c.want)
+		}
+	}
+
+	if _, err := StartRelay("sbx.kra.go.ke", http.DefaultTransport); err == nil {
+		t.Error("expected an error for a URL without a scheme")
+	}
+}
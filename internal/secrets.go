@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrSecretNotFound is returned when a secret is not present in a store
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore keeps credentials outside the plaintext config file
+type SecretStore interface {
+	// Name identifies the backend, e.g. "keyring" or "file"
+	Name() string
+	// Location describes where secrets are kept, for display
+	Location() string
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// KeyringStore stores secrets in the operating system keyring
+// (Secret Service on Linux, Keychain on macOS, Credential Manager on Windows)
+type KeyringStore struct {
+	Service string
+}
+
+// NewKeyringStore creates a keyring-backed store under the given service name
+func NewKeyringStore(service string) *KeyringStore {
+	return &KeyringStore{Service: service}
+}
+
+// Name returns "keyring"
+func (s *KeyringStore) Name() string { return "keyring" }
+
+// Location describes the keyring entry
+func (s *KeyringStore) Location() string { return "OS keyring (service " + s.Service + ")" }
+
+// Get retrieves a secret from the keyring
+func (s *KeyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(s.Service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("keyring unavailable: %w", err)
+	}
+	return value, nil
+}
+
+// Set stores a secret in the keyring
+func (s *KeyringStore) Set(key, value string) error {
+	if err := keyring.Set(s.Service, key, value); err != nil {
+		return fmt.Errorf("keyring unavailable: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a secret from the keyring
+func (s *KeyringStore) Delete(key string) error {
+	err := keyring.Delete(s.Service, key)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("keyring unavailable: %w", err)
+	}
+	return nil
+}
+
+// encryptedFile is the on-disk format of an EncryptedFileStore
+type encryptedFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// scrypt parameters for deriving the file key from the passphrase
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// EncryptedFileStore stores secrets in a file encrypted with AES-256-GCM under
+// a key derived from a passphrase with scrypt. It works on headless systems
+// without a keyring daemon.
+type EncryptedFileStore struct {
+	Path       string
+	Passphrase func() ([]byte, error) // called at most once, when the file is first accessed
+
+	mu      sync.Mutex
+	secrets map[string]string
+	key     []byte
+	salt    []byte
+}
+
+// NewEncryptedFileStore creates a store backed by the encrypted file at path
+func NewEncryptedFileStore(path string, passphrase func() ([]byte, error)) *EncryptedFileStore {
+	return &EncryptedFileStore{Path: path, Passphrase: passphrase}
+}
+
+// Name returns "file"
+func (s *EncryptedFileStore) Name() string { return "file" }
+
+// Location describes the encrypted file
+func (s *EncryptedFileStore) Location() string { return "encrypted file " + s.Path }
+
+// Get retrieves a secret from the encrypted file
+func (s *EncryptedFileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	value, ok := s.secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set stores a secret and rewrites the encrypted file
+func (s *EncryptedFileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.secrets[key] = value
+	return s.save()
+}
+
+// Delete removes a secret and rewrites the encrypted file
+func (s *EncryptedFileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.secrets[key]; !ok {
+		return nil
+	}
+	delete(s.secrets, key)
+	return s.save()
+}
+
+// load decrypts the file into memory on first use; a missing file is an empty store
+func (s *EncryptedFileStore) load() error {
+	if s.secrets != nil {
+		return nil
+	}
+
+	raw, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		passphrase, err := s.Passphrase()
+		if err != nil {
+			return err
+		}
+		s.salt = make([]byte, 16)
+		if _, err := rand.Read(s.salt); err != nil {
+			return err
+		}
+		if s.key, err = deriveKey(passphrase, s.salt); err != nil {
+			return err
+		}
+		s.secrets = make(map[string]string)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("secrets file %s is corrupt: %w", s.Path, err)
+	}
+	if file.Version != 1 || file.KDF != "scrypt" {
+		return fmt.Errorf("secrets file %s has an unsupported format", s.Path)
+	}
+
+	passphrase, err := s.Passphrase()
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets file (wrong passphrase?)")
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("secrets file %s is corrupt: %w", s.Path, err)
+	}
+
+	s.secrets, s.key, s.salt = secrets, key, file.Salt
+	return nil
+}
+
+// save encrypts the in-memory secrets with a fresh nonce and writes them atomically
+func (s *EncryptedFileStore) save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(encryptedFile{
+		Version: 1,
+		KDF:     "scrypt",
+		Salt:    s.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+// deriveKey derives the AES key from the passphrase
+func deriveKey(passphrase, salt []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("a passphrase is required for the encrypted secrets file")
+	}
+	return scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+}
+
+// newGCM creates an AES-GCM cipher for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
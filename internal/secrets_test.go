@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	passphrase := func() ([]byte, error) { return []byte("correct horse"), nil }
+
+	store := NewEncryptedFileStore(path, passphrase)
+	if err := store.Set("default/client_secret", "s3cr3t-value"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read secrets file: %v", err)
+	}
+	if bytes.Contains(raw, []byte("s3cr3t-value")) {
+		t.Fatalf("secret was written to disk in plaintext")
+	}
+
+	reopened := NewEncryptedFileStore(path, passphrase)
+	got, err := reopened.Get("default/client_secret")
+	if err != nil || got != "s3cr3t-value" {
+		t.Fatalf("Get = %q, %v; expected stored secret", got, err)
+	}
+	if _, err := reopened.Get("default/api_key"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected ErrSecretNotFound, got %v", err)
+	}
+
+	wrong := NewEncryptedFileStore(path, func() ([]byte, error) { return []byte("wrong"), nil })
+	if _, err := wrong.Get("default/client_secret"); err == nil {
+		t.Fatalf("expected decryption to fail with the wrong passphrase")
+	}
+
+	if err := reopened.Delete("default/client_secret"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := NewEncryptedFileStore(path, passphrase).Get("default/client_secret"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected deleted secret to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TokenRefreshMargin is how long before expiry a cached token is replaced
+const TokenRefreshMargin = time.Minute
+
+// defaultTokenLifetime is assumed when the token response has no expires_in
+const defaultTokenLifetime = time.Hour
+
+// Token is an OAuth client-credentials access token
+type Token struct {
+	AccessToken string          `json:"access_token"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	ClientID    string          `json:"client_id"`
+	TokenURL    string          `json:"token_url"`
+	Response    json.RawMessage `json:"response"` // body returned by the token endpoint
+}
+
+// Valid reports whether the token can still be used for at least margin
+func (t *Token) Valid(margin time.Duration) bool {
+	return t != nil && t.AccessToken != "" && time.Until(t.ExpiresAt) > margin
+}
+
+// TokenAuthError is returned when the token endpoint refuses the client
+// credentials
+type TokenAuthError struct {
+	StatusCode int // 401 or 403
+}
+
+func (e *TokenAuthError) Error() string {
+	return fmt.Sprintf("token request unauthorized (HTTP %d): check the client ID and secret", e.StatusCode)
+}
+
+// FetchToken requests a client-credentials token from tokenURL using HTTP
+// basic authentication, as the GavaConnect token endpoint expects
+func FetchToken(ctx context.Context, transport http.RoundTripper, tokenURL, clientID, clientSecret string) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token URL: %w", err)
+	}
+	req.SetBasicAuth(clientID, clientSecret)
+	req.Header.Set("Accept", "application/json")
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, &TokenAuthError{StatusCode: resp.StatusCode}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed: HTTP %d", resp.StatusCode)
+	}
+
+	var fields struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil || fields.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access token")
+	}
+
+	lifetime := defaultTokenLifetime
+	if seconds, err := strconv.ParseFloat(string(fields.ExpiresIn), 64); err == nil && seconds > 0 {
+		lifetime = time.Duration(seconds * float64(time.Second))
+	}
+
+	return &Token{
+		AccessToken: fields.AccessToken,
+		ExpiresAt:   time.Now().Add(lifetime),
+		ClientID:    clientID,
+		TokenURL:    tokenURL,
+		Response:    body,
+	}, nil
+}
+
+// TokenCache keeps a token in a SecretStore under Key
+type TokenCache struct {
+	Store SecretStore
+	Key   string
+}
+
+// Load returns the cached token, or nil if there is none
+func (c *TokenCache) Load() (*Token, error) {
+	raw, err := c.Store.Get(c.Key)
+	if errors.Is(err, ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal([]byte(raw), &token); err != nil {
+		return nil, fmt.Errorf("cached token is corrupt: %w", err)
+	}
+	return &token, nil
+}
+
+// Save stores token in the cache
+func (c *TokenCache) Save(token *Token) error {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return c.Store.Set(c.Key, string(raw))
+}
+
+// Delete removes the cached token
+func (c *TokenCache) Delete() error {
+	return c.Store.Delete(c.Key)
+}
+
+// TokenTransport answers requests for TokenURL from the token cache, fetching
+// and caching a new token only when the cached one is missing or near expiry.
+// All other requests pass through to Base. Cache failures are not fatal: the
+// token is then fetched as if there were no cache.
+type TokenTransport struct {
+	Base         http.RoundTripper
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Cache        *TokenCache     // optional
+	OnFetch      func(err error) // optional; called after every token request
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !sameEndpoint(req.URL, t.TokenURL) {
+		return t.base().RoundTrip(req)
+	}
+
+	token, err := t.Token(req.Context(), false)
+	if err != nil {
+		return nil, err
+	}
+	return tokenResponse(req, token)
+}
+
+// Token returns a valid token, from memory or the cache when possible.
+// With refresh, a new token is always fetched.
+func (t *TokenTransport) Token(ctx context.Context, refresh bool) (*Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !refresh {
+		if t.usable(t.token) {
+			return t.token, nil
+		}
+		if t.Cache != nil {
+			if cached, err := t.Cache.Load(); err == nil && t.usable(cached) {
+				t.token = cached
+				return cached, nil
+			}
+		}
+	}
+
+	token, err := FetchToken(ctx, t.base(), t.TokenURL, t.ClientID, t.ClientSecret)
+	if t.OnFetch != nil {
+		t.OnFetch(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.token = token
+	if t.Cache != nil {
+		_ = t.Cache.Save(token)
+	}
+	return token, nil
+}
+
+// usable reports whether token belongs to these credentials and is not near expiry
+func (t *TokenTransport) usable(token *Token) bool {
+	return token.Valid(TokenRefreshMargin) && token.ClientID == t.ClientID && token.TokenURL == t.TokenURL
+}
+
+func (t *TokenTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// sameEndpoint reports whether u addresses the same scheme, host and path as target
+func sameEndpoint(u *url.URL, target string) bool {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == parsed.Scheme && u.Host == parsed.Host && u.Path == parsed.Path
+}
+
+// tokenResponse replays the token endpoint's response body with expires_in
+// reduced to the token's remaining lifetime, keeping its original JSON type
+func tokenResponse(req *http.Request, token *Token) (*http.Response, error) {
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(token.Response, &body); err != nil {
+		body = map[string]interface{}{"access_token": token.AccessToken}
+	}
+
+	remaining := int(time.Until(token.ExpiresAt).Seconds())
+	if _, ok := body["expires_in"].(string); ok {
+		body["expires_in"] = strconv.Itoa(remaining)
+	} else {
+		body["expires_in"] = remaining
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(raw)),
+		ContentLength: int64(len(raw)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryStore is an in-memory SecretStore for tests
+type memoryStore map[string]string
+
+func (m memoryStore) Name() string     { return "memory" }
+func (m memoryStore) Location() string { return "memory" }
+func (m memoryStore) Get(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+func (m memoryStore) Set(key, value string) error { m[key] = value; return nil }
+func (m memoryStore) Delete(key string) error     { delete(m, key); return nil }
+
+func newTokenServer(t *testing.T, expiresIn string) (*httptest.Server, *int32) {
+	var issued int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":%s}`, n, expiresIn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestTokenTransportReusesCachedToken(t *testing.T) {
+	server, issued := newTokenServer(t, `"3599"`)
+	cache := &TokenCache{Store: memoryStore{}, Key: "default/oauth_token"}
+	tokenURL := server.URL + "/v1/token/generate?grant_type=client_credentials"
+
+	for i := 0; i < 2; i++ {
+		// A fresh transport per iteration stands in for a new CLI invocation
+		transport := &TokenTransport{TokenURL: tokenURL, ClientID: "id", ClientSecret: "secret", Cache: cache}
+		resp, err := (&http.Client{Transport: transport}).Get(tokenURL)
+		if err != nil {
+			t.Fatalf("token request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatalf("invalid token response %s: %v", body, err)
+		}
+		if fields["access_token"] != "token-1" {
+			t.Fatalf("expected cached token-1, got %v", fields["access_token"])
+		}
+		if _, ok := fields["expires_in"].(string); !ok {
+			t.Fatalf("expected expires_in to stay a string, got %T", fields["expires_in"])
+		}
+	}
+
+	if *issued != 1 {
+		t.Fatalf("expected one token to be issued, got %d", *issued)
+	}
+}
+
+func TestTokenTransportRefreshesNearExpiry(t *testing.T) {
+	server, issued := newTokenServer(t, "3599")
+	store := memoryStore{}
+	cache := &TokenCache{Store: store, Key: "default/oauth_token"}
+	tokenURL := server.URL + "/token"
+
+	stale := &Token{AccessToken: "old", ExpiresAt: time.Now().Add(30 * time.Second), ClientID: "id", TokenURL: tokenURL}
+	if err := cache.Save(stale); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	transport := &TokenTransport{TokenURL: tokenURL, ClientID: "id", ClientSecret: "secret", Cache: cache}
+	token, err := transport.Token(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Token returned error: %v", err)
+	}
+	if token.AccessToken != "token-1" || *issued != 1 {
+		t.Fatalf("expected a refreshed token, got %q after %d requests", token.AccessToken, *issued)
+	}
+
+	cached, err := cache.Load()
+	if err != nil || cached.AccessToken != "token-1" {
+		t.Fatalf("expected refreshed token to be cached, got %+v, %v", cached, err)
+	}
+}
+
+func TestFetchTokenUnauthorized(t *testing.T) {
+	server, _ := newTokenServer(t, "3599")
+
+	if _, err := FetchToken(context.Background(), nil, server.URL, "id", "wrong"); err == nil {
+		t.Fatalf("expected an error for bad credentials")
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchEntry is a TCC tracked for expiry, with the outcome of its last check
+type WatchEntry struct {
+	TCC       string     `json:"tcc"`
+	PIN       string     `json:"pin"`
+	Label     string     `json:"label,omitempty"`
+	AddedAt   time.Time  `json:"added_at"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Valid     *bool      `json:"valid,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"` // why the last check failed
+}
+
+// Name describes the entry by its label, or its PIN when it has none
+func (e *WatchEntry) Name() string {
+	if e.Label != "" {
+		return e.Label
+	}
+	return e.PIN
+}
+
+// Watchlist is the set of TCCs a profile monitors, stored as a JSON file
+type Watchlist struct {
+	path    string
+	Entries []WatchEntry `json:"entries"`
+}
+
+// LoadWatchlist reads the watchlist at path; a missing file is an empty list
+func LoadWatchlist(path string) (*Watchlist, error) {
+	w := &Watchlist{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watchlist: %w", err)
+	}
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("invalid watchlist %s: %w", path, err)
+	}
+	return w, nil
+}
+
+// Save writes the watchlist back to its file
+func (w *Watchlist) Save() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create watchlist directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(w.path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write watchlist: %w", err)
+	}
+	return nil
+}
+
+// Add adds an entry, or updates the PIN and label of the entry with the same
+// TCC. It reports whether the TCC was already watched.
+func (w *Watchlist) Add(entry WatchEntry) bool {
+	entry.TCC = strings.ToUpper(strings.TrimSpace(entry.TCC))
+	entry.PIN = strings.ToUpper(strings.TrimSpace(entry.PIN))
+
+	if i := w.index(entry.TCC); i != -1 {
+		existing := &w.Entries[i]
+		if existing.PIN != entry.PIN {
+			// A different PIN makes the last check meaningless
+			existing.CheckedAt, existing.Valid, existing.ExpiresAt, existing.Error = nil, nil, nil, ""
+		}
+		existing.PIN = entry.PIN
+		if entry.Label != "" {
+			existing.Label = entry.Label
+		}
+		return true
+	}
+
+	if entry.AddedAt.IsZero() {
+		entry.AddedAt = time.Now().UTC()
+	}
+	w.Entries = append(w.Entries, entry)
+	sort.SliceStable(w.Entries, func(i, j int) bool { return w.Entries[i].TCC < w.Entries[j].TCC })
+	return false
+}
+
+// Remove removes the entry for tcc, reporting whether it was watched
+func (w *Watchlist) Remove(tcc string) bool {
+	i := w.index(tcc)
+	if i == -1 {
+		return false
+	}
+	w.Entries = append(w.Entries[:i], w.Entries[i+1:]...)
+	return true
+}
+
+func (w *Watchlist) index(tcc string) int {
+	tcc = strings.ToUpper(strings.TrimSpace(tcc))
+	for i := range w.Entries {
+		if w.Entries[i].TCC == tcc {
+			return i
+		}
+	}
+	return -1
+}
+
+// expiryLayouts are the date formats accepted for TCC expiry dates
+var expiryLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006",
+	"02-01-2006",
+	"2 Jan 2006",
+	"02-Jan-2006",
+}
+
+// ParseExpiryDate parses an expiry date as returned by the API. Dates are
+// read as calendar dates; a time of day, if any, is dropped.
+func ParseExpiryDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range expiryLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised expiry date %q", s)
+}
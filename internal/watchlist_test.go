@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlist", "default.json")
+
+	w, err := LoadWatchlist(path)
+	if err != nil {
+		t.Fatalf("LoadWatchlist returned error: %v", err)
+	}
+	if len(w.Entries) != 0 {
+		t.Fatalf("expected an empty watchlist, got %+v", w.Entries)
+	}
+
+	if w.Add(WatchEntry{TCC: "kra202400002", PIN: "p051234567a", Label: "Acme"}) {
+		t.Error("expected a new entry")
+	}
+	w.Add(WatchEntry{TCC: "KRA202400001", PIN: "P059876543B"})
+
+	valid := true
+	expires := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	w.Entries[1].Valid, w.Entries[1].ExpiresAt = &valid, &expires
+
+	// Re-adding with the same PIN keeps the last check and the label
+	if !w.Add(WatchEntry{TCC: "KRA202400002", PIN: "P051234567A"}) {
+		t.Error("expected the TCC to be watched already")
+	}
+	if w.Entries[1].Label != "Acme" || w.Entries[1].ExpiresAt == nil {
+		t.Errorf("unexpected entry after re-adding: %+v", w.Entries[1])
+	}
+
+	if err := w.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded, err := LoadWatchlist(path)
+	if err != nil {
+		t.Fatalf("LoadWatchlist returned error: %v", err)
+	}
+	if len(loaded.Entries) != 2 || loaded.Entries[0].TCC != "KRA202400001" || loaded.Entries[1].PIN != "P051234567A" {
+		t.Fatalf("unexpected entries after reload: %+v", loaded.Entries)
+	}
+	if !loaded.Entries[1].ExpiresAt.Equal(expires) {
+		t.Errorf("expected expiry to survive a reload, got %v", loaded.Entries[1].ExpiresAt)
+	}
+
+	if !loaded.Remove("kra202400001") || loaded.Remove("KRA202400001") {
+		t.Error("expected the TCC to be removed exactly once")
+	}
+}
+
+func TestParseExpiryDate(t *testing.T) {
+	want := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	for _, input := range []string{"2024-06-30", "2024-06-30T23:59:59Z", "30/06/2024", "30 Jun 2024", "2024-06-30 08:00:00"} {
+		got, err := ParseExpiryDate(input)
+		if err != nil {
+			t.Errorf("ParseExpiryDate(%q) returned error: %v", input, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("ParseExpiryDate(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	if _, err := ParseExpiryDate("next year"); err == nil {
+		t.Error("expected an error for an unrecognised date")
+	}
+}